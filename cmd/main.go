@@ -1,15 +1,27 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"github.com/diillson/api-gateway-go/initialization"
 	"github.com/diillson/api-gateway-go/internal/auth"
 	"github.com/diillson/api-gateway-go/internal/database"
 	"github.com/diillson/api-gateway-go/internal/handler"
+	"github.com/diillson/api-gateway-go/internal/health"
 	"github.com/diillson/api-gateway-go/internal/middleware"
+	"github.com/diillson/api-gateway-go/internal/routestore"
+	"github.com/diillson/api-gateway-go/internal/tlsreload"
 	"github.com/diillson/api-gateway-go/pkg/config"
 	"github.com/diillson/api-gateway-go/pkg/logging"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 ) // This should be the same secret key used in the IsAuthenticated middleware
 
 func main() {
@@ -21,16 +33,58 @@ func main() {
 		return
 	}
 
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Fatal("Failed to load configuration", zap.Error(err))
+	}
+
+	if cfg.Auth.JWTSecret != "" {
+		auth.JwtKey = []byte(cfg.Auth.JWTSecret)
+	} else {
+		logger.Warn("AG_AUTH_JWT_SECRET not set, using the insecure default key; generate one with cmd/tools/gensecret")
+	}
+
 	db, err := database.NewDatabase()
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
 
 	r := gin.Default()
-	r.Use(auth.IsAuthenticated())
+	r.RedirectTrailingSlash = cfg.Routes.TrailingSlash == config.TrailingSlashRedirect
+	// Headers globais vêm primeiro para aparecerem também nas respostas de erro dos outros middlewares
+	if len(cfg.Server.ResponseHeaders) > 0 {
+		r.Use(middleware.ResponseHeaders(cfg.Server.ResponseHeaders))
+	}
+	r.Use(middleware.MaxPathLength(cfg.Server.MaxPathLength, logger))
+	r.Use(middleware.MaxHeaders(cfg.Server.MaxHeaderCount, cfg.Server.MaxHeaderBytes, logger))
+	r.Use(middleware.RequestID(cfg.Server.RequestIDInboundHeaders, cfg.Server.RequestIDHeader))
+
+	if cfg.Security.HeadersEnabled {
+		r.Use(middleware.SecurityHeaders())
+	}
+	if cfg.CORS.Enabled {
+		r.Use(middleware.CORS(cfg.CORS))
+	}
+
+	// Health checks são registrados antes da autenticação para não exigir token
+	healthChecker := health.NewHealthChecker(db, logger, cfg.Server.ReadinessConcurrency, cfg.Server.ReadinessTimeout)
+	r.GET("/health/live", healthChecker.LivenessCheck)
+	r.GET("/health/ready", healthChecker.ReadinessCheck)
+
+	// O limite global vem depois dos health checks para que probes não sejam descartados sob carga
+	r.Use(middleware.MaxInFlight(cfg.Server.MaxInFlight, logger))
 
-	// Inicialização das rotas do routes.json
-	err = initialization.LoadAndSaveRoutes(r, "./routes/routes.json", db, logger)
+	var errorRecorder *middleware.ErrorRecorder
+	if cfg.Debug.RecentErrorsEnabled {
+		errorRecorder = middleware.NewErrorRecorder(cfg.Debug.RecentErrorsSize)
+		r.Use(errorRecorder.Record)
+	}
+
+	r.Use(auth.IsAuthenticated(cfg.Auth))
+	r.POST("/auth/logout", auth.Logout)
+
+	// Inicialização das rotas do arquivo de rotas (JSON ou YAML)
+	err = initialization.LoadAndSaveRoutes(r, cfg.Routes, db, logger)
 	if err != nil {
 		logger.Error("Failed to load routes", zap.Error(err))
 	}
@@ -43,40 +97,57 @@ func main() {
 
 	logger.Info("Generated JWT token:", zap.String("token", token))
 
-	routes, err := db.GetRoutes()
-	if err != nil {
+	// Handler e middlewares leem as rotas do mesmo store, recarregado a cada escrita do admin
+	routeStore := routestore.New(db, cfg.Routes.TrailingSlash)
+	if _, _, err := routeStore.Reload(); err != nil {
 		logger.Fatal("Failed to load routes from database", zap.Error(err))
 	}
+	routes := routeStore.All()
 
-	httpHandler := handler.NewHandler(db, logger)
+	httpHandler := handler.NewHandler(db, logger, cfg, routeStore)
 
-	routesMap := make(map[string]*config.Route)
-	for _, route := range routes {
-		routesMap[route.Path] = route
-	}
 	// Passando a instância do banco de dados para o middleware
-	mw := middleware.NewMiddleware(logger, routesMap, db)
+	mw := middleware.NewMiddleware(logger, routeStore, db, cfg)
 
 	for _, route := range routes {
-		if !handler.RouteExists(r, route.Methods, route.Path) {
+		for _, path := range route.MatchPaths(cfg.Routes.TrailingSlash) {
+			if handler.RouteExists(r, route.Methods, path) {
+				logger.Warn("Route already exists", zap.String("path", path))
+				continue
+			}
+			registered := make(map[string]bool)
 			for _, method := range route.Methods {
-				switch method {
-				case "GET":
-					r.GET(route.Path, mw.RateLimit, mw.Analytics, func(c *gin.Context) {
-						httpHandler.ServeHTTP(c.Writer, c.Request)
-					})
-				case "POST":
-					r.POST(route.Path, mw.RateLimit, mw.Analytics, func(c *gin.Context) {
-						httpHandler.ServeHTTP(c.Writer, c.Request)
-					})
-					// Add other HTTP methods as needed
+				method = strings.ToUpper(method)
+				if !config.IsValidMethod(method) {
+					logger.Warn("Skipping invalid HTTP method", zap.String("path", path), zap.String("method", method))
+					continue
+				}
+				// O gin entra em pânico se o mesmo método for registrado duas vezes (ex.: "get" e "GET")
+				if registered[method] {
+					continue
 				}
+				registered[method] = true
+				r.Handle(method, path, mw.MatchRoute, mw.RateLimit, mw.Analytics, func(c *gin.Context) {
+					httpHandler.ServeHTTP(c.Writer, c.Request)
+				})
 			}
-		} else {
-			logger.Warn("Route already exists", zap.String("path", route.Path))
 		}
 	}
 
+	// Reconciliação periódica do cache de rotas com o banco, como rede de segurança
+	reconcileCtx, stopReconcile := context.WithCancel(context.Background())
+	defer stopReconcile()
+	if cfg.Routes.ReconcileInterval > 0 {
+		go mw.ReconcileRoutes(reconcileCtx, cfg.Routes.ReconcileInterval)
+	}
+
+	// Limpeza periódica dos limites por IP de clientes inativos
+	cleanupCtx, stopCleanup := context.WithCancel(context.Background())
+	defer stopCleanup()
+	if cfg.RateLimit.VisitorTTL > 0 {
+		go mw.CleanupVisitors(cleanupCtx, cfg.RateLimit.VisitorTTL)
+	}
+
 	admin := r.Group("/admin")
 	admin.Use(mw.AuthenticateAdmin) // ajustado para usar o middleware diretamente
 
@@ -84,9 +155,89 @@ func main() {
 	admin.GET("/apis", httpHandler.ListAPIs)
 	admin.PUT("/update", httpHandler.UpdateAPI)
 	admin.DELETE("/delete", httpHandler.DeleteAPI)
+	admin.POST("/routes/bulk", httpHandler.BulkRoutes)
+	admin.GET("/routes/dependencies", httpHandler.GetRouteDependencies)
+	admin.GET("/routes/traffic", mw.RouteTraffic)
+	admin.GET("/ratelimit/status", mw.RateLimitStatusAPI)
+	admin.GET("/backends", httpHandler.GetBackendLoad)
+	admin.GET("/dns", httpHandler.GetDNSStats)
 	admin.GET("/metrics", httpHandler.GetMetrics)
+	admin.GET("/health/history", healthChecker.DependencyHistory)
+	admin.GET("/auth/problems", auth.HeaderProblems)
+	admin.POST("/auth/revoke", auth.RevokeUserAPI)
+	admin.GET("/limits/rejections", middleware.LimitRejections)
+	admin.GET("/config", httpHandler.GetConfig)
+	if errorRecorder != nil {
+		admin.GET("/debug/recent-errors", errorRecorder.RecentErrors)
+	}
+	// Reset é destrutivo: só existe quando habilitado explicitamente
+	if cfg.Server.AdminResetEnabled {
+		admin.POST("/reset", httpHandler.ResetAPI(mw.Reset))
+	}
+
+	// Rotas carregadas e registradas: o readiness pode passar a responder UP
+	if cfg.Server.ReadyAfterWarmup {
+		go func() {
+			<-httpHandler.Warmed()
+			logger.Info("Backend warmup finished, marking gateway as ready")
+			healthChecker.MarkReady()
+		}()
+	} else {
+		healthChecker.MarkReady()
+	}
 
-	if err := r.Run(":8080"); err != nil {
-		logger.Fatal("Failed to start server", zap.Error(err))
+	server := &http.Server{
+		Addr:    ":8080",
+		Handler: middleware.NormalizeMethod(cfg.Server.MethodOverrideHeader, r),
+		// Limite rígido na leitura; o middleware MaxHeaders responde 431 com detalhes e métrica
+		MaxHeaderBytes: cfg.Server.MaxHeaderBytes,
 	}
+
+	// Com certificado próprio o gateway serve HTTPS, relendo o par do disco quando ele é rotacionado
+	if cfg.Server.TLSCertFile != "" {
+		reloader, err := tlsreload.NewReloader(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile, cfg.Server.TLSReloadInterval, logger)
+		if err != nil {
+			logger.Fatal("Failed to load TLS certificate", zap.Error(err))
+		}
+		server.TLSConfig = &tls.Config{GetCertificate: reloader.GetCertificate}
+	}
+
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Fatal("Failed to start server", zap.Error(err))
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	// Marca o readiness como DOWN e aguarda os load balancers perceberem antes do drain
+	logger.Info("Shutting down server", zap.Duration("preShutdownDelay", cfg.Server.PreShutdownDelay))
+	healthChecker.StartShutdown()
+	time.Sleep(cfg.Server.PreShutdownDelay)
+
+	// Recusa novas chamadas ao proxy e dá um prazo para as ativas terminarem antes de cancelá-las
+	logger.Info("Draining proxied requests", zap.Int("active", httpHandler.ActiveProxyRequests()))
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.Server.ProxyDrainTimeout)
+	if err := httpHandler.Drain(drainCtx); err != nil {
+		logger.Warn("Proxy drain timed out, cancelling remaining upstream requests",
+			zap.Int("active", httpHandler.ActiveProxyRequests()))
+	}
+	drainCancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("Server forced to shutdown", zap.Error(err))
+	}
+
+	logger.Info("Server exited")
 }
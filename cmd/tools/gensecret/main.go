@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// minRecommendedBytes is the smallest secret recommended for HS256 (256 bits).
+const minRecommendedBytes = 32
+
+func main() {
+	length := flag.Int("length", 64, "number of random bytes in the secret")
+	flag.Parse()
+
+	if *length <= 0 {
+		fmt.Fprintln(os.Stderr, "length must be positive")
+		os.Exit(1)
+	}
+	if *length < minRecommendedBytes {
+		fmt.Fprintf(os.Stderr, "warning: secrets shorter than %d bytes are not recommended for HS256\n", minRecommendedBytes)
+	}
+
+	secret := make([]byte, *length)
+	if _, err := rand.Read(secret); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate secret: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println(base64.StdEncoding.EncodeToString(secret))
+}
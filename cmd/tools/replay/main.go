@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/diillson/api-gateway-go/internal/handler"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// maxLineBytes bounds a single recording; bodies are already capped when recorded.
+const maxLineBytes = 16 << 20
+
+func main() {
+	file := flag.String("file", "./recordings.jsonl", "recording file written by the gateway in test mode")
+	target := flag.String("target", "http://localhost:8080", "base URL of the gateway to replay against")
+	authorization := flag.String("authorization", "", "Authorization header sent in place of the redacted one")
+	route := flag.String("route", "", "replay only recordings of this route")
+	timeout := flag.Duration("timeout", 30*time.Second, "timeout of each replayed request")
+	flag.Parse()
+
+	f, err := os.Open(*file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open recordings: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	client := &http.Client{Timeout: *timeout}
+	base := strings.TrimSuffix(*target, "/")
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxLineBytes)
+
+	var replayed, mismatched, failed int
+	for line := 1; scanner.Scan(); line++ {
+		var rec handler.Recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: invalid recording: %v\n", line, err)
+			failed++
+			continue
+		}
+		if *route != "" && rec.Route != *route {
+			continue
+		}
+		if rec.RequestBodyTruncated {
+			fmt.Printf("SKIP %s %s: request body was not recorded in full\n", rec.Method, rec.URI)
+			continue
+		}
+
+		status, err := replay(client, base, rec, *authorization)
+		replayed++
+		switch {
+		case err != nil:
+			failed++
+			fmt.Printf("FAIL %s %s: %v\n", rec.Method, rec.URI, err)
+		case status != rec.Status:
+			mismatched++
+			fmt.Printf("DIFF %s %s: recorded %d, got %d\n", rec.Method, rec.URI, rec.Status, status)
+		default:
+			fmt.Printf("OK   %s %s: %d\n", rec.Method, rec.URI, status)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to read recordings: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("\n%d replayed, %d status mismatches, %d failures\n", replayed, mismatched, failed)
+	if mismatched > 0 || failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func replay(client *http.Client, base string, rec handler.Recording, authorization string) (int, error) {
+	req, err := http.NewRequest(rec.Method, base+rec.URI, bytes.NewReader(rec.RequestBody))
+	if err != nil {
+		return 0, err
+	}
+	for name, values := range rec.RequestHeader {
+		// Valores mascarados na gravação não são reenviados
+		if len(values) == 1 && values[0] == "[REDACTED]" {
+			continue
+		}
+		req.Header[name] = values
+	}
+	req.Header.Del("Content-Length")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -2,14 +2,21 @@ package initialization
 
 import (
 	"encoding/json"
+	"fmt"
 	"github.com/diillson/api-gateway-go/internal/database"
 	"github.com/diillson/api-gateway-go/internal/handler"
+	"github.com/diillson/api-gateway-go/internal/workers"
 	"github.com/diillson/api-gateway-go/pkg/config"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
+// LoadRoutes reads route definitions from a JSON or YAML file, chosen by the
+// file extension.
 func LoadRoutes(filePath string) ([]config.Route, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -18,18 +25,50 @@ func LoadRoutes(filePath string) ([]config.Route, error) {
 	defer file.Close()
 
 	routes := []config.Route{}
-	decoder := json.NewDecoder(file)
-	err = decoder.Decode(&routes)
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".yaml", ".yml":
+		err = yaml.NewDecoder(file).Decode(&routes)
+	default:
+		err = json.NewDecoder(file).Decode(&routes)
+	}
 	return routes, err
 }
 
-func LoadAndSaveRoutes(r *gin.Engine, filePath string, db *database.Database, logger *zap.Logger) error {
-	routes, err := LoadRoutes(filePath)
+func LoadAndSaveRoutes(r *gin.Engine, cfg config.RoutesConfig, db *database.Database, logger *zap.Logger) error {
+	routes, err := LoadRoutes(cfg.File)
 	if err != nil {
 		return err
 	}
 
+	// Rotas inválidas (ex.: sem métodos) rejeitariam todas as requisições; são ignoradas com aviso
+	valid := routes[:0]
 	for _, route := range routes {
+		if err := route.Validate(); err != nil {
+			logger.Warn("Skipping invalid route", zap.String("path", route.Path), zap.Error(err))
+			continue
+		}
+		valid = append(valid, route)
+	}
+	routes = valid
+
+	existing, err := db.GetRoutes()
+	if err != nil {
+		return err
+	}
+
+	// Padrões sobrepostos tornam o casamento de rotas imprevisível
+	if conflicts := config.FindRouteConflicts(existing, routes); len(conflicts) > 0 {
+		for _, conflict := range conflicts {
+			logger.Warn("Route conflict detected", zap.String("conflict", conflict.String()))
+		}
+		if cfg.ConflictPolicy == config.ConflictPolicyReject {
+			return fmt.Errorf("found %d conflicting routes in %s", len(conflicts), cfg.File)
+		}
+	}
+
+	// A importação é sequencial: o SQLite aceita um único escritor por vez
+	step := workers.ProgressStep(len(routes))
+	for i, route := range routes {
 		// Verificar e adicionar a rota ao banco de dados
 		if !handler.RouteExists(r, route.Methods, route.Path) {
 			err = db.AddRoute(&route)
@@ -42,6 +81,10 @@ func LoadAndSaveRoutes(r *gin.Engine, filePath string, db *database.Database, lo
 			logger.Warn("Route already exists", zap.String("path", route.Path))
 			// Não retornar erro, apenas continuar para a próxima rota
 		}
+
+		if (i+1)%step == 0 || i+1 == len(routes) {
+			logger.Info("Route import progress", zap.Int("done", i+1), zap.Int("total", len(routes)))
+		}
 	}
 
 	return nil // Retornar nil ao final indicando que não houve erro crítico
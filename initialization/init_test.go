@@ -0,0 +1,130 @@
+package initialization
+
+import (
+	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeRoutesFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadRoutesYAMLMatchesJSON(t *testing.T) {
+	jsonFile := writeRoutesFile(t, "routes.json", `[
+  {
+    "path": "/api/users",
+    "serviceURL": "http://users.internal",
+    "methods": ["GET", "POST"],
+    "headers": ["Content-Type"],
+    "description": "Users",
+    "isActive": true,
+    "tags": ["identity"],
+    "rateLimit": 10,
+    "rateLimitPeriod": 60000000000,
+    "upstreams": [{"url": "http://users-a.internal", "weight": 3}, {"url": "http://users-b.internal"}],
+    "errorPages": [{"status": 502, "responseStatus": 503, "body": "unavailable"}]
+  },
+  {
+    "path": "/api/orders/*",
+    "serviceURL": "http://orders.internal",
+    "methods": ["GET"],
+    "isActive": false
+  }
+]`)
+	yamlFile := writeRoutesFile(t, "routes.yaml", `
+- path: /api/users
+  serviceURL: http://users.internal
+  methods: [GET, POST]
+  headers: [Content-Type]
+  description: Users
+  isActive: true
+  tags: [identity]
+  rateLimit: 10
+  rateLimitPeriod: 1m
+  upstreams:
+    - url: http://users-a.internal
+      weight: 3
+    - url: http://users-b.internal
+  errorPages:
+    - status: 502
+      responseStatus: 503
+      body: unavailable
+- path: /api/orders/*
+  serviceURL: http://orders.internal
+  methods: [GET]
+  isActive: false
+`)
+
+	fromJSON, err := LoadRoutes(jsonFile)
+	if err != nil {
+		t.Fatalf("loading JSON: %v", err)
+	}
+	fromYAML, err := LoadRoutes(yamlFile)
+	if err != nil {
+		t.Fatalf("loading YAML: %v", err)
+	}
+
+	if len(fromJSON) != 2 {
+		t.Fatalf("loaded %d routes from JSON, want 2", len(fromJSON))
+	}
+	if fromJSON[0].RateLimitPeriod != time.Minute {
+		t.Fatalf("JSON rateLimitPeriod %v, want 1m", fromJSON[0].RateLimitPeriod)
+	}
+	if !reflect.DeepEqual(fromJSON, fromYAML) {
+		t.Fatalf("YAML routes differ from JSON ones:\njson: %+v\nyaml: %+v", fromJSON, fromYAML)
+	}
+}
+
+func TestLoadRoutesYMLExtension(t *testing.T) {
+	path := writeRoutesFile(t, "routes.YML", "- path: /health\n  serviceURL: http://health.internal\n  methods: [GET]\n")
+
+	routes, err := LoadRoutes(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Path != "/health" {
+		t.Fatalf("got %+v, want the /health route", routes)
+	}
+}
+
+func TestLoadAndSaveRoutesSkipsEmptyMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := database.Open(filepath.Join(t.TempDir(), "routes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := writeRoutesFile(t, "routes.json", `[
+  {"path": "/users", "serviceURL": "http://users.internal", "methods": ["GET"], "isActive": true},
+  {"path": "/empty", "serviceURL": "http://empty.internal", "methods": [], "isActive": true},
+  {"path": "/null", "serviceURL": "http://null.internal", "methods": null, "isActive": true}
+]`)
+
+	core, logs := observer.New(zap.WarnLevel)
+	if err := LoadAndSaveRoutes(gin.New(), config.RoutesConfig{File: file}, db, zap.New(core)); err != nil {
+		t.Fatal(err)
+	}
+
+	routes, err := db.GetRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 1 || routes[0].Path != "/users" {
+		t.Fatalf("stored %+v, want only /users", routes)
+	}
+	if n := logs.FilterMessage("Skipping invalid route").Len(); n != 2 {
+		t.Fatalf("%d routes flagged, want 2", n)
+	}
+}
@@ -1,11 +1,14 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"github.com/diillson/api-gateway-go/pkg/config"
 	"github.com/diillson/api-gateway-go/pkg/logging"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"strings"
 )
@@ -17,7 +20,10 @@ type Claims struct {
 
 var JwtKey = []byte("your-secret-key")
 
-func IsAuthenticated() gin.HandlerFunc {
+// UsernameKey is the gin context key holding the authenticated username.
+const UsernameKey = "username"
+
+func IsAuthenticated(cfg config.AuthConfig) gin.HandlerFunc {
 	logger, err := logging.NewLogger()
 	if err != nil {
 		// handle error
@@ -25,14 +31,78 @@ func IsAuthenticated() gin.HandlerFunc {
 		return nil
 	}
 
+	trustedNets := ParseCIDRs(cfg.TrustedProxyCIDRs, logger)
+
+	// Autenticação desligada: nenhum token é validado e todas as requisições são anônimas
+	if !cfg.Enabled {
+		logger.Warn("Authentication is disabled, all requests are treated as anonymous",
+			zap.String("user", cfg.AnonymousUser))
+		return func(c *gin.Context) {
+			stripUntrustedIdentity(c, cfg.TrustedHeader, trustedNets)
+			c.Set(UsernameKey, cfg.AnonymousUser)
+			c.Next()
+		}
+	}
+
+	var introspect *introspector
+	if cfg.Mode == config.AuthModeIntrospection {
+		introspect = newIntrospector(cfg)
+	}
+
+	var jwks *jwksVerifier
+	if cfg.JWKSURL != "" {
+		jwks = newJWKSVerifier(cfg)
+	}
+
 	return func(c *gin.Context) {
+		// Tráfego interno da malha: o sidecar já autenticou e informa o usuário no header
+		if cfg.TrustedHeader != "" && IsTrustedSource(c.RemoteIP(), trustedNets) {
+			if user := c.GetHeader(cfg.TrustedHeader); user != "" {
+				c.Set(UsernameKey, user)
+				c.Next()
+				return
+			}
+		}
+		stripUntrustedIdentity(c, cfg.TrustedHeader, trustedNets)
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header not provided"})
+			rejectAuth(c, ProblemMissingHeader)
+			return
+		}
+
+		scheme, tokenString, _ := strings.Cut(authHeader, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			logger.Info("Rejected Authorization header", zap.String("reason", ProblemWrongScheme))
+			rejectAuth(c, ProblemWrongScheme)
+			return
+		}
+		tokenString = strings.TrimSpace(tokenString)
+		if tokenString == "" {
+			logger.Info("Rejected Authorization header", zap.String("reason", ProblemEmptyToken))
+			rejectAuth(c, ProblemEmptyToken)
+			return
+		}
+
+		// Tokens RS256 de IdPs externos são validados com as chaves publicadas no JWKS
+		if jwks != nil && tokenAlg(tokenString) == jwt.SigningMethodRS256.Alg() {
+			username, err := jwks.validate(c.Request.Context(), tokenString)
+			if errors.Is(err, errJWKSUnavailable) {
+				logger.Error("Failed to fetch JWKS", zap.Error(err))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Signing keys unavailable"})
+				return
+			}
+			if err != nil {
+				reason := tokenProblem(err)
+				logger.Info("Rejected Authorization header", zap.String("reason", reason), zap.Error(err))
+				rejectAuth(c, reason)
+				return
+			}
+			c.Set(UsernameKey, username)
+			c.Next()
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		claims := &Claims{}
 
 		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -42,18 +112,83 @@ func IsAuthenticated() gin.HandlerFunc {
 			return JwtKey, nil
 		})
 
+		// Modo introspection: tokens que não são JWTs do gateway (ex.: tokens admin) são tratados como opacos
+		if err != nil && introspect != nil {
+			username, active, err := introspect.validate(c.Request.Context(), tokenString)
+			if err != nil {
+				logger.Error("Token introspection failed", zap.Error(err))
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Token introspection unavailable"})
+				return
+			}
+			if !active {
+				logger.Info("Rejected Authorization header", zap.String("reason", ProblemInactiveToken))
+				rejectAuth(c, ProblemInactiveToken)
+				return
+			}
+			c.Set(UsernameKey, username)
+			c.Next()
+			return
+		}
+
 		if err != nil {
-			logger.Error("Error parsing token: %v", zap.Error(err))
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Error parsing token"})
+			reason := tokenProblem(err)
+			logger.Info("Rejected Authorization header", zap.String("reason", reason), zap.Error(err))
+			rejectAuth(c, reason)
 			return
 		}
 
 		if !token.Valid {
-			logger.Error("Invalid token", zap.Error(err))
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			logger.Info("Rejected Authorization header", zap.String("reason", ProblemInvalidToken))
+			rejectAuth(c, ProblemInvalidToken)
 			return
 		}
 
+		if IsRevoked(claims) {
+			logger.Info("Rejected Authorization header", zap.String("reason", ProblemRevokedToken))
+			rejectAuth(c, ProblemRevokedToken)
+			return
+		}
+
+		c.Set(UsernameKey, claims.Username)
 		c.Next()
 	}
 }
+
+// ParseCIDRs parses the trusted proxy networks, logging and skipping invalid
+// entries.
+func ParseCIDRs(cidrs []string, logger *zap.Logger) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Error("Invalid trusted proxy CIDR, ignoring", zap.String("cidr", cidr), zap.Error(err))
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
+
+// stripUntrustedIdentity removes the trusted identity header from requests
+// that don't come from a trusted proxy, so a client can't impersonate a user
+// to backends that read it.
+func stripUntrustedIdentity(c *gin.Context, header string, nets []*net.IPNet) {
+	if header != "" && !IsTrustedSource(c.RemoteIP(), nets) {
+		c.Request.Header.Del(header)
+	}
+}
+
+// IsTrustedSource checks the direct peer address (never X-Forwarded-For, which
+// the client controls) against the trusted networks.
+func IsTrustedSource(remoteIP string, nets []*net.IPNet) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const trustedHeader = "X-Authenticated-User"
+
+// newAuthEngine returns an engine whose /whoami answers with the
+// authenticated user and the identity header that would reach the backend.
+func newAuthEngine(cfg config.AuthConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(IsAuthenticated(cfg))
+	r.GET("/whoami", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": c.GetString(UsernameKey), "header": c.GetHeader(trustedHeader)})
+	})
+	return r
+}
+
+func TestTrustedIdentityHeader(t *testing.T) {
+	token, err := GenerateJWT("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := newAuthEngine(config.AuthConfig{
+		Enabled:           true,
+		TrustedHeader:     trustedHeader,
+		TrustedProxyCIDRs: []string{"10.0.0.0/8"},
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		identity   string
+		token      string
+		wantStatus int
+		wantBody   string
+	}{
+		{"trusted proxy", "10.1.2.3:5000", "alice", "", http.StatusOK, `{"header":"alice","user":"alice"}`},
+		{"untrusted without token", "203.0.113.9:5000", "alice", "", http.StatusUnauthorized, ""},
+		{"untrusted with token", "203.0.113.9:5000", "alice", token, http.StatusOK, `{"header":"","user":"bob"}`},
+		{"trusted without header", "10.1.2.3:5000", "", token, http.StatusOK, `{"header":"","user":"bob"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.identity != "" {
+				req.Header.Set(trustedHeader, tt.identity)
+			}
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Fatalf("body %s, want %s", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestUntrustedIdentityHeaderStrippedWhenAuthDisabled(t *testing.T) {
+	r := newAuthEngine(config.AuthConfig{
+		AnonymousUser:     "anonymous",
+		TrustedHeader:     trustedHeader,
+		TrustedProxyCIDRs: []string{"10.0.0.0/8"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.RemoteAddr = "203.0.113.9:5000"
+	req.Header.Set(trustedHeader, "admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if want := `{"header":"","user":"anonymous"}`; w.Body.String() != want {
+		t.Fatalf("body %s, want %s", w.Body.String(), want)
+	}
+}
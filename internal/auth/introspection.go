@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	introspectionTimeout   = 5 * time.Second
+	introspectionCacheSize = 10000
+)
+
+type introspectionResponse struct {
+	Active   bool   `json:"active"`
+	Username string `json:"username"`
+	Sub      string `json:"sub"`
+	Exp      int64  `json:"exp"`
+}
+
+type introspectionEntry struct {
+	username string
+	expires  time.Time
+}
+
+// introspector validates opaque tokens against an RFC 7662 introspection
+// endpoint, caching active tokens until they expire (capped by the cache TTL).
+type introspector struct {
+	cfg    config.AuthConfig
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]introspectionEntry
+}
+
+func newIntrospector(cfg config.AuthConfig) *introspector {
+	return &introspector{
+		cfg:    cfg,
+		client: &http.Client{Timeout: introspectionTimeout},
+		cache:  make(map[string]introspectionEntry),
+	}
+}
+
+// validate returns the user the token belongs to, or active=false when the
+// identity provider reports the token as inactive.
+func (i *introspector) validate(ctx context.Context, token string) (username string, active bool, err error) {
+	now := time.Now()
+
+	i.mu.Lock()
+	entry, ok := i.cache[token]
+	i.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.username, true, nil
+	}
+
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.cfg.IntrospectionURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.cfg.IntrospectionClientID != "" {
+		req.SetBasicAuth(i.cfg.IntrospectionClientID, i.cfg.IntrospectionClientSecret)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("introspection endpoint returned %d", resp.StatusCode)
+	}
+
+	var result introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", false, fmt.Errorf("invalid introspection response: %w", err)
+	}
+	if !result.Active {
+		return "", false, nil
+	}
+
+	username = result.Username
+	if username == "" {
+		username = result.Sub
+	}
+
+	expires := now.Add(i.cfg.IntrospectionCacheTTL)
+	if result.Exp > 0 {
+		if exp := time.Unix(result.Exp, 0); exp.Before(expires) {
+			expires = exp
+		}
+	}
+	i.store(token, introspectionEntry{username: username, expires: expires}, now)
+
+	return username, true, nil
+}
+
+func (i *introspector) store(token string, entry introspectionEntry, now time.Time) {
+	if !now.Before(entry.expires) {
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// Cache cheio: descarta os expirados e, se ainda não houver espaço, não guarda
+	if len(i.cache) >= introspectionCacheSize {
+		for key, cached := range i.cache {
+			if !now.Before(cached.expires) {
+				delete(i.cache, key)
+			}
+		}
+		if len(i.cache) >= introspectionCacheSize {
+			return
+		}
+	}
+	i.cache[token] = entry
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newIntrospectionServer is a mock RFC 7662 endpoint that knows a few opaque
+// tokens and counts the introspections per token.
+func newIntrospectionServer(t *testing.T) (*httptest.Server, func(token string) int) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		calls = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "gateway" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		token := r.PostFormValue("token")
+		mu.Lock()
+		calls[token]++
+		mu.Unlock()
+
+		var response map[string]interface{}
+		switch token {
+		case "opaque-alice":
+			response = map[string]interface{}{"active": true, "username": "alice", "exp": time.Now().Add(time.Hour).Unix()}
+		case "opaque-sub":
+			response = map[string]interface{}{"active": true, "sub": "svc-billing"}
+		case "opaque-expired":
+			response = map[string]interface{}{"active": true, "username": "carol", "exp": time.Now().Add(-time.Second).Unix()}
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		default:
+			response = map[string]interface{}{"active": false}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(token string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls[token]
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	server, calls := newIntrospectionServer(t)
+	r := newAuthEngine(config.AuthConfig{
+		Enabled:                   true,
+		Mode:                      config.AuthModeIntrospection,
+		IntrospectionURL:          server.URL,
+		IntrospectionClientID:     "gateway",
+		IntrospectionClientSecret: "s3cret",
+		IntrospectionCacheTTL:     time.Minute,
+	})
+	jwtToken, err := GenerateJWT("bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	whoami := func(token string) (int, string) {
+		req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		var body struct{ User string }
+		json.Unmarshal(w.Body.Bytes(), &body)
+		return w.Code, body.User
+	}
+
+	tests := []struct {
+		token      string
+		wantStatus int
+		wantUser   string
+	}{
+		{"opaque-alice", http.StatusOK, "alice"},
+		{"opaque-sub", http.StatusOK, "svc-billing"},
+		{"opaque-revoked", http.StatusUnauthorized, ""},
+		{"broken", http.StatusServiceUnavailable, ""},
+		// JWTs do próprio gateway continuam valendo sem consultar o endpoint
+		{jwtToken, http.StatusOK, "bob"},
+	}
+	for _, tt := range tests {
+		if status, user := whoami(tt.token); status != tt.wantStatus || user != tt.wantUser {
+			t.Fatalf("token %s: got %d %q, want %d %q", tt.token, status, user, tt.wantStatus, tt.wantUser)
+		}
+	}
+	if n := calls(jwtToken); n != 0 {
+		t.Fatalf("gateway JWT introspected %d times", n)
+	}
+
+	// Tokens ativos ficam em cache até o exp; os já expirados são consultados de novo
+	for i := 0; i < 3; i++ {
+		whoami("opaque-alice")
+		whoami("opaque-expired")
+	}
+	if n := calls("opaque-alice"); n != 1 {
+		t.Fatalf("active token introspected %d times, want 1 (cached)", n)
+	}
+	if n := calls("opaque-expired"); n != 3 {
+		t.Fatalf("expired token introspected %d times, want 3 (never cached)", n)
+	}
+}
@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	jwksTimeout = 5 * time.Second
+	// jwksMinRefresh impede que tokens com kid desconhecido forcem um download do JWKS a cada requisição
+	jwksMinRefresh = 10 * time.Second
+	// jwksMaxAge é quanto o conjunto baixado vale; depois disso chaves removidas pelo IdP deixam de ser aceitas
+	jwksMaxAge = 10 * time.Minute
+)
+
+// errJWKSUnavailable is returned when the signing keys can't be fetched, so
+// the token can't be judged either way.
+var errJWKSUnavailable = errors.New("JWKS unavailable")
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// jwksClaims are the claims read from identity-provider tokens. Unlike
+// Claims, aud may be a single string or a list.
+type jwksClaims struct {
+	PreferredUsername string `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// jwksVerifier validates RS256 tokens issued by an identity provider with the
+// public keys it publishes at a JWKS URL. Keys are cached by kid and fetched
+// again, at most every jwksMinRefresh, when a token names a kid not in the
+// cache (key rotation) or the cached set is older than jwksMaxAge.
+type jwksVerifier struct {
+	cfg    config.AuthConfig
+	client *http.Client
+
+	mu   sync.Mutex
+	keys map[string]*rsa.PublicKey
+	// lastFetch e fetchErr são da última tentativa de download, bem-sucedida ou não
+	lastFetch time.Time
+	fetchErr  error
+}
+
+func newJWKSVerifier(cfg config.AuthConfig) *jwksVerifier {
+	return &jwksVerifier{
+		cfg:    cfg,
+		client: &http.Client{Timeout: jwksTimeout},
+		keys:   make(map[string]*rsa.PublicKey),
+	}
+}
+
+// tokenAlg returns the alg header of a token without verifying it.
+func tokenAlg(tokenString string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+	alg, _ := token.Header["alg"].(string)
+	return alg
+}
+
+// validate verifies the token signature, expiry and the configured issuer and
+// audience, returning the user it belongs to (preferred_username or sub).
+func (v *jwksVerifier) validate(ctx context.Context, tokenString string) (string, error) {
+	claims := &jwksClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		return "", err
+	}
+	if !token.Valid {
+		return "", jwt.NewValidationError("token is invalid", jwt.ValidationErrorClaimsInvalid)
+	}
+
+	if v.cfg.Issuer != "" && !claims.VerifyIssuer(v.cfg.Issuer, true) {
+		return "", jwt.NewValidationError("token issuer is not accepted", jwt.ValidationErrorIssuer)
+	}
+	if v.cfg.Audience != "" && !claims.VerifyAudience(v.cfg.Audience, true) {
+		return "", jwt.NewValidationError("token audience is not accepted", jwt.ValidationErrorAudience)
+	}
+
+	if claims.PreferredUsername != "" {
+		return claims.PreferredUsername, nil
+	}
+	return claims.Subject, nil
+}
+
+// key returns the public key with the given kid, refreshing the cached set
+// when the kid is unknown or the set is too old. A token without kid is
+// accepted only while the set has a single key.
+func (v *jwksVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key := v.lookup(kid); key != nil && time.Since(v.lastFetch) < jwksMaxAge {
+		return key, nil
+	}
+	if time.Since(v.lastFetch) >= jwksMinRefresh {
+		v.lastFetch = time.Now()
+		v.fetchErr = v.refresh(ctx)
+	}
+	// Com o IdP fora do ar, as chaves já conhecidas continuam valendo
+	if key := v.lookup(kid); key != nil {
+		return key, nil
+	}
+	if v.fetchErr != nil {
+		return nil, fmt.Errorf("%w: %v", errJWKSUnavailable, v.fetchErr)
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+func (v *jwksVerifier) lookup(kid string) *rsa.PublicKey {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key
+		}
+	}
+	return v.keys[kid]
+}
+
+// refresh replaces the cached keys with the ones currently published. Must be
+// called with v.mu held.
+func (v *jwksVerifier) refresh(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.cfg.JWKSURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("JWKS endpoint returned %d", resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid JWKS response: %w", err)
+	}
+
+	// Só chaves RSA de assinatura interessam; as demais são ignoradas
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := k.rsaPublicKey()
+		if err != nil {
+			return fmt.Errorf("invalid JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+
+	v.keys = keys
+	return nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("unsupported exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
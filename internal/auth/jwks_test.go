@@ -0,0 +1,191 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/golang-jwt/jwt/v4"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// jwksServer publishes the public keys it is given and can be made to fail.
+type jwksServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	failing bool
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PublicKey) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var set jwkSet
+		for kid, key := range s.keys {
+			set.Keys = append(set.Keys, jwk{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(keys map[string]*rsa.PublicKey, failing bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys, s.failing = keys, failing
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// signIdPToken signs an identity-provider token for sub with the given
+// method, key and kid.
+func signIdPToken(t *testing.T, method jwt.SigningMethod, key interface{}, kid, sub string, expires time.Time) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, jwt.RegisteredClaims{
+		Subject:   sub,
+		ExpiresAt: jwt.NewNumericDate(expires),
+	})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWKSTokens(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PublicKey{"k1": &key.PublicKey})
+	r := newAuthEngine(config.AuthConfig{Enabled: true, JWKSURL: server.URL})
+
+	// Ataque de confusão de algoritmo: HS256 com a chave pública, que é conhecida, como segredo
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+
+	hour := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		token  string
+		status int
+		reason string
+	}{
+		{"valid RS256", signIdPToken(t, jwt.SigningMethodRS256, key, "k1", "alice", hour), http.StatusOK, ""},
+		{"unknown kid", signIdPToken(t, jwt.SigningMethodRS256, key, "k9", "alice", hour), http.StatusUnauthorized, ProblemInvalidToken},
+		{"HS256 signed with the public key", signIdPToken(t, jwt.SigningMethodHS256, publicPEM, "k1", "alice", hour), http.StatusUnauthorized, ProblemInvalidSignature},
+		{"expired", signIdPToken(t, jwt.SigningMethodRS256, key, "k1", "alice", time.Now().Add(-time.Minute)), http.StatusUnauthorized, ProblemExpiredToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d (%s), want %d", w.Code, w.Body.String(), tt.status)
+			}
+			var body struct {
+				User string `json:"user"`
+				Code string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if tt.status == http.StatusOK && body.User != "alice" {
+				t.Fatalf("user %q, want alice", body.User)
+			}
+			if body.Code != tt.reason {
+				t.Fatalf("reason %q, want %q", body.Code, tt.reason)
+			}
+		})
+	}
+}
+
+func TestJWKSFetchFailure(t *testing.T) {
+	key := newRSAKey(t)
+	server := newJWKSServer(t, nil)
+	server.publish(nil, true)
+	r := newAuthEngine(config.AuthConfig{Enabled: true, JWKSURL: server.URL})
+
+	req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+signIdPToken(t, jwt.SigningMethodRS256, key, "k1", "alice", time.Now().Add(time.Hour)))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	// Sem as chaves o token não pode ser julgado: indisponível, não inválido
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d (%s), want 503", w.Code, w.Body.String())
+	}
+}
+
+func TestJWKSDropsRemovedKeys(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PublicKey{"old": &oldKey.PublicKey})
+	v := newJWKSVerifier(config.AuthConfig{JWKSURL: server.URL})
+	ctx := context.Background()
+
+	hour := time.Now().Add(time.Hour)
+	oldToken := signIdPToken(t, jwt.SigningMethodRS256, oldKey, "old", "alice", hour)
+	newToken := signIdPToken(t, jwt.SigningMethodRS256, newKey, "new", "bob", hour)
+	age := func() {
+		v.mu.Lock()
+		v.lastFetch = time.Now().Add(-jwksMaxAge)
+		v.mu.Unlock()
+	}
+
+	if _, err := v.validate(ctx, oldToken); err != nil {
+		t.Fatalf("token signed with the published key: %v", err)
+	}
+
+	// O IdP troca a chave; a antiga só vale enquanto o conjunto em cache é recente
+	server.publish(map[string]*rsa.PublicKey{"new": &newKey.PublicKey}, false)
+	age()
+	if _, err := v.validate(ctx, oldToken); err == nil || errors.Is(err, errJWKSUnavailable) {
+		t.Fatalf("token signed with a removed key: error %v, want it rejected", err)
+	}
+	if user, err := v.validate(ctx, newToken); err != nil || user != "bob" {
+		t.Fatalf("token signed with the new key: user %q, error %v", user, err)
+	}
+
+	// Com o IdP fora do ar, as chaves conhecidas continuam valendo
+	server.publish(nil, true)
+	age()
+	if _, err := v.validate(ctx, newToken); err != nil {
+		t.Fatalf("known key while the JWKS endpoint is down: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"sync"
+)
+
+// Reasons reported when the Authorization header can't be accepted.
+const (
+	ProblemMissingHeader    = "missing_header"
+	ProblemWrongScheme      = "wrong_scheme"
+	ProblemEmptyToken       = "empty_token"
+	ProblemMalformedToken   = "malformed_token"
+	ProblemExpiredToken     = "expired_token"
+	ProblemInvalidSignature = "invalid_signature"
+	ProblemInvalidToken     = "invalid_token"
+	ProblemInactiveToken    = "inactive_token"
+	ProblemRevokedToken     = "revoked_token"
+)
+
+var problemMessages = map[string]string{
+	ProblemMissingHeader:    "Authorization header not provided",
+	ProblemWrongScheme:      "Authorization header must use the Bearer scheme",
+	ProblemEmptyToken:       "Bearer token is empty",
+	ProblemMalformedToken:   "Token is malformed",
+	ProblemExpiredToken:     "Token has expired",
+	ProblemInvalidSignature: "Token signature is invalid",
+	ProblemInvalidToken:     "Invalid token",
+	ProblemInactiveToken:    "Token is not active",
+	ProblemRevokedToken:     "Token has been revoked",
+}
+
+// authHeaderProblems counts the rejected Authorization headers by reason
+// (the auth_header_problem metric).
+var authHeaderProblems = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+// rejectAuth counts the problem and aborts with 401, returning the reason in
+// the body so client integrations can tell the cases apart.
+func rejectAuth(c *gin.Context, reason string) {
+	authHeaderProblems.Lock()
+	authHeaderProblems.counts[reason]++
+	authHeaderProblems.Unlock()
+
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": problemMessages[reason], "code": reason})
+}
+
+// tokenProblem maps a JWT parsing error to a problem reason.
+func tokenProblem(err error) string {
+	var validationErr *jwt.ValidationError
+	if !errors.As(err, &validationErr) {
+		return ProblemInvalidToken
+	}
+	switch {
+	case validationErr.Errors&jwt.ValidationErrorMalformed != 0:
+		return ProblemMalformedToken
+	case validationErr.Errors&jwt.ValidationErrorExpired != 0:
+		return ProblemExpiredToken
+	case validationErr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		return ProblemInvalidSignature
+	default:
+		return ProblemInvalidToken
+	}
+}
+
+// HeaderProblems returns how many requests were rejected for each
+// Authorization header problem.
+func HeaderProblems(c *gin.Context) {
+	authHeaderProblems.Lock()
+	counts := make(map[string]int64, len(authHeaderProblems.counts))
+	for reason, count := range authHeaderProblems.counts {
+		counts[reason] = count
+	}
+	authHeaderProblems.Unlock()
+
+	c.JSON(http.StatusOK, gin.H{"auth_header_problem": counts})
+}
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// signGatewayToken signs a gateway token for username expiring at expires
+// with key.
+func signGatewayToken(t *testing.T, username string, expires time.Time, key []byte) string {
+	t.Helper()
+	claims := &Claims{
+		Username: username,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expires.Unix(),
+			IssuedAt:  time.Now().Unix(),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestAuthProblemReasons(t *testing.T) {
+	r := newAuthEngine(config.AuthConfig{Enabled: true})
+
+	tests := []struct {
+		name          string
+		authorization string
+		reason        string
+	}{
+		{"missing header", "", ProblemMissingHeader},
+		{"wrong scheme", "Basic YWxpY2U6c2VjcmV0", ProblemWrongScheme},
+		{"empty token", "Bearer   ", ProblemEmptyToken},
+		{"malformed token", "Bearer not.a.jwt", ProblemMalformedToken},
+		{"expired token", "Bearer " + signGatewayToken(t, "alice", time.Now().Add(-time.Minute), JwtKey), ProblemExpiredToken},
+		{"bad signature", "Bearer " + signGatewayToken(t, "alice", time.Now().Add(time.Hour), []byte("another-key")), ProblemInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authHeaderProblems.Lock()
+			before := authHeaderProblems.counts[tt.reason]
+			authHeaderProblems.Unlock()
+
+			req := httptest.NewRequest(http.MethodGet, "/whoami", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Fatalf("status %d, want 401", w.Code)
+			}
+			var body struct {
+				Error string `json:"error"`
+				Code  string `json:"code"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.reason || body.Error != problemMessages[tt.reason] {
+				t.Fatalf("body %s, want reason %s", w.Body.String(), tt.reason)
+			}
+
+			authHeaderProblems.Lock()
+			after := authHeaderProblems.counts[tt.reason]
+			authHeaderProblems.Unlock()
+			if after != before+1 {
+				t.Fatalf("auth_header_problem{%s} went from %d to %d, want one more", tt.reason, before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// revocations holds the gateway tokens revoked before their expiry: single
+// tokens by jti, and every token of a user issued before a given time.
+// Entries are dropped once the tokens they cover have expired anyway.
+var revocations = struct {
+	sync.Mutex
+	tokens map[string]time.Time
+	users  map[string]time.Time
+}{tokens: make(map[string]time.Time), users: make(map[string]time.Time)}
+
+// IsRevoked reports whether a gateway token was revoked, individually or as
+// part of a revoke-all for its user. Tokens without iat count as issued
+// before any revoke-all. It only covers the HS256 tokens issued by the
+// gateway: tokens validated through JWKS or introspection are never checked
+// here and must be revoked at their identity provider.
+func IsRevoked(claims *Claims) bool {
+	revocations.Lock()
+	defer revocations.Unlock()
+
+	if _, ok := revocations.tokens[claims.Id]; ok && claims.Id != "" {
+		return true
+	}
+	if before, ok := revocations.users[claims.Username]; ok {
+		return time.Unix(claims.IssuedAt, 0).Before(before)
+	}
+	return false
+}
+
+// revokeToken revokes one token until it expires.
+func revokeToken(id string, expires time.Time) {
+	revocations.Lock()
+	defer revocations.Unlock()
+
+	pruneRevocations(time.Now())
+	revocations.tokens[id] = expires
+}
+
+// RevokeUser revokes every token issued to username up to now.
+func RevokeUser(username string) {
+	revocations.Lock()
+	defer revocations.Unlock()
+
+	now := time.Now()
+	pruneRevocations(now)
+	// Compara em segundos, a precisão do iat, para incluir os tokens emitidos neste mesmo segundo
+	revocations.users[username] = now.Truncate(time.Second).Add(time.Second)
+}
+
+// pruneRevocations drops entries whose tokens have all expired. Must be called
+// with revocations locked.
+func pruneRevocations(now time.Time) {
+	for id, expires := range revocations.tokens {
+		if now.After(expires) {
+			delete(revocations.tokens, id)
+		}
+	}
+	for username, before := range revocations.users {
+		if now.After(before.Add(tokenTTL)) {
+			delete(revocations.users, username)
+		}
+	}
+}
+
+// Logout revokes the gateway token that authenticated the request. Tokens
+// from an identity provider must be revoked there.
+func Logout(c *gin.Context) {
+	tokenString := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.NewValidationError("unexpected signing method", jwt.ValidationErrorSignatureInvalid)
+		}
+		return JwtKey, nil
+	})
+	if err != nil || !token.Valid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only tokens issued by the gateway can be revoked here"})
+		return
+	}
+	if claims.Id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token has no jti and can't be revoked individually"})
+		return
+	}
+
+	revokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeUserAPI revokes every token issued to the user given in ?user=.
+// Tokens issued afterwards are accepted.
+func RevokeUserAPI(c *gin.Context) {
+	username := c.Query("user")
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User query parameter required"})
+		return
+	}
+
+	RevokeUser(username)
+	c.JSON(http.StatusOK, gin.H{"user": username, "revokedBefore": time.Now()})
+}
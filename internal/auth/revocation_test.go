@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLogoutEngine serves /whoami and /auth/logout behind authentication, as
+// cmd/main.go does.
+func newLogoutEngine() *gin.Engine {
+	r := newAuthEngine(config.AuthConfig{Enabled: true})
+	r.POST("/auth/logout", Logout)
+	return r
+}
+
+func sendWithToken(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLogoutRevokesOnlyThatToken(t *testing.T) {
+	r := newLogoutEngine()
+	token, err := GenerateJWT("logout-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateJWT("logout-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := sendWithToken(r, http.MethodPost, "/auth/logout", token); w.Code != http.StatusNoContent {
+		t.Fatalf("logout: status %d (%s), want 204", w.Code, w.Body.String())
+	}
+	w := sendWithToken(r, http.MethodGet, "/whoami", token)
+	if w.Code != http.StatusUnauthorized || !containsReason(w, ProblemRevokedToken) {
+		t.Fatalf("token after logout: status %d (%s), want 401 %s", w.Code, w.Body.String(), ProblemRevokedToken)
+	}
+	if w := sendWithToken(r, http.MethodGet, "/whoami", other); w.Code != http.StatusOK {
+		t.Fatalf("another token of the same user: status %d, want 200", w.Code)
+	}
+}
+
+func TestLogoutRejectsTokensWithoutJTI(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &Claims{
+		Username:       "no-jti",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()},
+	}).SignedString(JwtKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if w := sendWithToken(newLogoutEngine(), http.MethodPost, "/auth/logout", token); w.Code != http.StatusBadRequest {
+		t.Fatalf("logout without jti: status %d, want 400", w.Code)
+	}
+}
+
+func TestRevokeUserRejectsEarlierTokens(t *testing.T) {
+	r := newAuthEngine(config.AuthConfig{Enabled: true})
+	before, err := GenerateJWT("revoked-user")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	RevokeUser("revoked-user")
+
+	if w := sendWithToken(r, http.MethodGet, "/whoami", before); w.Code != http.StatusUnauthorized || !containsReason(w, ProblemRevokedToken) {
+		t.Fatalf("token issued before the revoke-all: status %d (%s), want 401 %s", w.Code, w.Body.String(), ProblemRevokedToken)
+	}
+
+	// O iat tem precisão de segundos: o corte cai no segundo seguinte ao revoke-all
+	revocations.Lock()
+	cutoff := revocations.users["revoked-user"]
+	revocations.Unlock()
+	if IsRevoked(&Claims{Username: "revoked-user", StandardClaims: jwt.StandardClaims{IssuedAt: cutoff.Unix()}}) {
+		t.Fatal("token issued after the revoke-all is revoked")
+	}
+	if IsRevoked(&Claims{Username: "another-user", StandardClaims: jwt.StandardClaims{IssuedAt: cutoff.Unix() - 1}}) {
+		t.Fatal("revoke-all reached another user")
+	}
+}
+
+func TestRevocationsPrunedAfterExpiry(t *testing.T) {
+	revokeToken("expired-jti", time.Now().Add(-time.Second))
+	revocations.Lock()
+	revocations.users["expired-user"] = time.Now().Add(-tokenTTL - time.Minute)
+	revocations.Unlock()
+
+	// Cada nova revogação descarta as entradas cujos tokens já expiraram
+	revokeToken("live-jti", time.Now().Add(time.Hour))
+
+	revocations.Lock()
+	defer revocations.Unlock()
+	if _, ok := revocations.tokens["expired-jti"]; ok {
+		t.Fatal("revocation of an expired token kept")
+	}
+	if _, ok := revocations.users["expired-user"]; ok {
+		t.Fatal("revoke-all older than the token lifetime kept")
+	}
+	if _, ok := revocations.tokens["live-jti"]; !ok {
+		t.Fatal("revocation of a live token dropped")
+	}
+}
+
+func containsReason(w *httptest.ResponseRecorder, reason string) bool {
+	return strings.Contains(w.Body.String(), `"code":"`+reason+`"`)
+}
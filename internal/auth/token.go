@@ -1,14 +1,27 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"github.com/golang-jwt/jwt/v4"
 	"time"
 )
 
+// tokenTTL is how long a token issued by the gateway stays valid.
+const tokenTTL = 24 * time.Hour
+
 // GenerateJWT creates a new JWT for a given username
 func GenerateJWT(username string) (string, error) {
 	// Setting the token expiration time
-	expirationTime := time.Now().Add(24 * time.Hour)
+	now := time.Now()
+	expirationTime := now.Add(tokenTTL)
+
+	// O jti identifica o token para que ele possa ser revogado no logout
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
 
 	// Creating the claims for the token, including the username and expiration time
 	claims := &Claims{
@@ -16,6 +29,8 @@ func GenerateJWT(username string) (string, error) {
 		StandardClaims: jwt.StandardClaims{
 			// Including the expiration time in Unix time
 			ExpiresAt: expirationTime.Unix(),
+			IssuedAt:  now.Unix(),
+			Id:        hex.EncodeToString(id),
 		},
 	}
 
@@ -25,3 +40,29 @@ func GenerateJWT(username string) (string, error) {
 	// Converting the token into a string
 	return token.SignedString(JwtKey)
 }
+
+// ErrTokenRevoked is returned by ParseGatewayToken for a revoked token.
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// ParseGatewayToken validates a token issued by the gateway, HS256-signed with
+// JwtKey and not revoked, and returns its claims. This is the check the admin
+// API applies.
+func ParseGatewayToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.NewValidationError("unexpected signing method", jwt.ValidationErrorSignatureInvalid)
+		}
+		return JwtKey, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if IsRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
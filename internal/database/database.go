@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,6 +10,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrVersionConflict is returned when a route was changed after the version
+// supplied in the update was read.
+var ErrVersionConflict = errors.New("route was modified concurrently")
+
+// ErrRouteNotFound is returned when no route exists at the given path.
+var ErrRouteNotFound = errors.New("route not found")
+
 type Database struct {
 	DB *gorm.DB
 }
@@ -21,7 +29,12 @@ func (db *Database) UpdateMetrics(route *config.Route) error {
 }
 
 func NewDatabase() (*Database, error) {
-	db, err := gorm.Open(sqlite.Open("./routes.db"), &gorm.Config{})
+	return Open("./routes.db")
+}
+
+// Open opens, creating it if needed, the SQLite database at path.
+func Open(path string) (*Database, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
@@ -45,15 +58,25 @@ func (db *Database) GetRoutes() ([]*config.Route, error) {
 		return nil, errors.New("database not initialized")
 	}
 
+	return loadRoutes(db.DB)
+}
+
+func loadRoutes(tx *gorm.DB) ([]*config.Route, error) {
 	var routeEntities []struct {
 		config.Route
-		MethodsJSON         string `gorm:"column:methods"`
-		HeadersJSON         string `gorm:"column:headers"`
-		RequiredHeadersJSON string `gorm:"column:required_headers"`
+		MethodsJSON          string `gorm:"column:methods"`
+		HeadersJSON          string `gorm:"column:headers"`
+		RequiredHeadersJSON  string `gorm:"column:required_headers"`
+		TagsJSON             string `gorm:"column:tags"`
+		HeaderAllowlistJSON  string `gorm:"column:response_header_allowlist"`
+		HeaderDenylistJSON   string `gorm:"column:response_header_denylist"`
+		RateLimitMethodsJSON string `gorm:"column:rate_limit_methods"`
+		ErrorPagesJSON       string `gorm:"column:error_pages"`
+		UpstreamsJSON        string `gorm:"column:upstreams"`
 	}
 
 	// Query usando métodos GORM
-	result := db.DB.Table("routes").Scan(&routeEntities)
+	result := tx.Table("routes").Scan(&routeEntities)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -69,6 +92,37 @@ func (db *Database) GetRoutes() ([]*config.Route, error) {
 		if err := json.Unmarshal([]byte(entity.RequiredHeadersJSON), &entity.RequiredHeaders); err != nil {
 			return nil, err
 		}
+		// Rotas criadas antes da coluna de tags existir ficam com o valor vazio
+		if entity.TagsJSON != "" {
+			if err := json.Unmarshal([]byte(entity.TagsJSON), &entity.Tags); err != nil {
+				return nil, err
+			}
+		}
+		if entity.HeaderAllowlistJSON != "" {
+			if err := json.Unmarshal([]byte(entity.HeaderAllowlistJSON), &entity.ResponseHeaderAllowlist); err != nil {
+				return nil, err
+			}
+		}
+		if entity.HeaderDenylistJSON != "" {
+			if err := json.Unmarshal([]byte(entity.HeaderDenylistJSON), &entity.ResponseHeaderDenylist); err != nil {
+				return nil, err
+			}
+		}
+		if entity.RateLimitMethodsJSON != "" {
+			if err := json.Unmarshal([]byte(entity.RateLimitMethodsJSON), &entity.RateLimitMethods); err != nil {
+				return nil, err
+			}
+		}
+		if entity.ErrorPagesJSON != "" {
+			if err := json.Unmarshal([]byte(entity.ErrorPagesJSON), &entity.ErrorPages); err != nil {
+				return nil, err
+			}
+		}
+		if entity.UpstreamsJSON != "" {
+			if err := json.Unmarshal([]byte(entity.UpstreamsJSON), &entity.Upstreams); err != nil {
+				return nil, err
+			}
+		}
 		route := entity.Route
 		routes = append(routes, &route)
 	}
@@ -76,6 +130,19 @@ func (db *Database) GetRoutes() ([]*config.Route, error) {
 	return routes, nil
 }
 
+// CountActiveRoutes counts active routes without loading them.
+func (db *Database) CountActiveRoutes(ctx context.Context) (int, error) {
+	if db == nil || db.DB == nil {
+		return 0, errors.New("database not initialized")
+	}
+
+	var count int64
+	if err := db.DB.WithContext(ctx).Model(&config.Route{}).Where("is_active = ?", true).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count active routes: %w", err)
+	}
+	return int(count), nil
+}
+
 func (db *Database) AddRoute(route *config.Route) error {
 	// Convertendo os slices para JSON
 	methods, err := json.Marshal(route.Methods)
@@ -115,23 +182,79 @@ func (db *Database) AddRoute(route *config.Route) error {
 		return errors.New("failed to marshal required headers: " + err.Error())
 	}
 
+	tags, err := json.Marshal(route.Tags)
+	if err != nil {
+		return errors.New("failed to marshal tags: " + err.Error())
+	}
+
+	headerAllowlist, err := json.Marshal(route.ResponseHeaderAllowlist)
+	if err != nil {
+		return errors.New("failed to marshal response header allowlist: " + err.Error())
+	}
+
+	headerDenylist, err := json.Marshal(route.ResponseHeaderDenylist)
+	if err != nil {
+		return errors.New("failed to marshal response header denylist: " + err.Error())
+	}
+
+	rateLimitMethods, err := json.Marshal(route.RateLimitMethods)
+	if err != nil {
+		return errors.New("failed to marshal rate limit methods: " + err.Error())
+	}
+
+	errorPages, err := json.Marshal(route.ErrorPages)
+	if err != nil {
+		return errors.New("failed to marshal error pages: " + err.Error())
+	}
+
+	upstreams, err := json.Marshal(route.Upstreams)
+	if err != nil {
+		return errors.New("failed to marshal upstreams: " + err.Error())
+	}
+
 	// Criando um mapa para armazenar os valores que serão salvos no DB
 	data := map[string]interface{}{
-		"path":             route.Path,
-		"service_url":      route.ServiceURL,
-		"methods":          string(methods),
-		"headers":          string(headers),
-		"description":      route.Description,
-		"is_active":        route.IsActive,
-		"call_count":       route.CallCount,
-		"total_response":   route.TotalResponse,
-		"required_headers": string(requiredHeaders),
+		"path":              route.Path,
+		"service_url":       route.ServiceURL,
+		"methods":           string(methods),
+		"headers":           string(headers),
+		"description":       route.Description,
+		"is_active":         route.IsActive,
+		"call_count":        route.CallCount,
+		"total_response":    route.TotalResponse,
+		"required_headers":  string(requiredHeaders),
+		"mirror_url":        route.MirrorURL,
+		"mirror_percent":    route.MirrorPercent,
+		"ca_cert_file":      route.CACertFile,
+		"require_https":     route.RequireHTTPS,
+		"tags":              string(tags),
+		"route_group":       route.Group,
+		"coalesce":          route.Coalesce,
+		"cache_responses":   route.CacheResponses,
+		"streaming":         route.Streaming,
+		"rate_limit":        route.RateLimit,
+		"rate_limit_period": route.RateLimitPeriod,
+
+		"response_header_allowlist": string(headerAllowlist),
+		"response_header_denylist":  string(headerDenylist),
+		"request_schema":            route.RequestSchema,
+		"preserve_host_header":      route.PreserveHostHeader,
+		"rate_limit_methods":        string(rateLimitMethods),
+		"deprecated":                route.Deprecated,
+		"sunset_at":                 route.SunsetAt,
+		"upstream_proxy":            route.UpstreamProxy,
+		"timeout":                   route.Timeout,
+		"adaptive_timeout":          route.AdaptiveTimeout,
+		"error_pages":               string(errorPages),
+		"upstreams":                 string(upstreams),
+		"version":                   1,
 	}
 
 	// Armazenando os dados no banco de dados
 	if err := db.DB.Model(&config.Route{}).Create(&data).Error; err != nil {
 		return errors.New("failed to add route: " + err.Error())
 	}
+	route.Version = 1
 
 	return nil
 }
@@ -156,18 +279,88 @@ func (db *Database) UpdateRoute(route *config.Route) error {
 		return err
 	}
 
-	if err := db.DB.Model(&config.Route{}).
-		Where("path = ?", route.Path).
+	tagsJson, err := json.Marshal(route.Tags)
+	if err != nil {
+		return err
+	}
+
+	headerAllowlistJson, err := json.Marshal(route.ResponseHeaderAllowlist)
+	if err != nil {
+		return err
+	}
+
+	headerDenylistJson, err := json.Marshal(route.ResponseHeaderDenylist)
+	if err != nil {
+		return err
+	}
+
+	rateLimitMethodsJson, err := json.Marshal(route.RateLimitMethods)
+	if err != nil {
+		return err
+	}
+
+	errorPagesJson, err := json.Marshal(route.ErrorPages)
+	if err != nil {
+		return err
+	}
+
+	upstreamsJson, err := json.Marshal(route.Upstreams)
+	if err != nil {
+		return err
+	}
+
+	// A versão só avança se ninguém alterou a rota desde a leitura (lock otimista)
+	result := db.DB.Model(&config.Route{}).
+		Where("path = ? AND version = ?", route.Path, route.Version).
 		Updates(map[string]interface{}{
-			"service_url":      route.ServiceURL,
-			"methods":          methodsJson,
-			"headers":          headersJson, // Certifique-se de que isso é incluído, mesmo que esteja vazio
-			"description":      route.Description,
-			"is_active":        route.IsActive,
-			"required_headers": requiredHeadersJson,
-		}).Error; err != nil {
-		return fmt.Errorf("failed to update route: %w", err)
+			"service_url":       route.ServiceURL,
+			"methods":           methodsJson,
+			"headers":           headersJson, // Certifique-se de que isso é incluído, mesmo que esteja vazio
+			"description":       route.Description,
+			"is_active":         route.IsActive,
+			"required_headers":  requiredHeadersJson,
+			"mirror_url":        route.MirrorURL,
+			"mirror_percent":    route.MirrorPercent,
+			"ca_cert_file":      route.CACertFile,
+			"require_https":     route.RequireHTTPS,
+			"tags":              tagsJson,
+			"route_group":       route.Group,
+			"coalesce":          route.Coalesce,
+			"cache_responses":   route.CacheResponses,
+			"streaming":         route.Streaming,
+			"rate_limit":        route.RateLimit,
+			"rate_limit_period": route.RateLimitPeriod,
+
+			"response_header_allowlist": headerAllowlistJson,
+			"response_header_denylist":  headerDenylistJson,
+			"request_schema":            route.RequestSchema,
+			"preserve_host_header":      route.PreserveHostHeader,
+			"rate_limit_methods":        rateLimitMethodsJson,
+			"deprecated":                route.Deprecated,
+			"sunset_at":                 route.SunsetAt,
+			"upstream_proxy":            route.UpstreamProxy,
+			"timeout":                   route.Timeout,
+			"adaptive_timeout":          route.AdaptiveTimeout,
+			"error_pages":               errorPagesJson,
+			"upstreams":                 upstreamsJson,
+			"version":                   gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update route: %w", result.Error)
 	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := db.DB.Model(&config.Route{}).Where("path = ?", route.Path).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to update route: %w", err)
+		}
+		if count > 0 {
+			return ErrVersionConflict
+		}
+		return ErrRouteNotFound
+	}
+
+	route.Version++
 	return nil
 }
 
@@ -187,3 +380,53 @@ func (db *Database) DeleteRoute(path string) error {
 	}
 	return nil
 }
+
+type BulkAction string
+
+const (
+	BulkEnable  BulkAction = "enable"
+	BulkDisable BulkAction = "disable"
+	BulkDelete  BulkAction = "delete"
+)
+
+// BulkApply applies action to every route accepted by match inside a single
+// transaction and returns the affected paths.
+func (db *Database) BulkApply(action BulkAction, match func(*config.Route) bool) ([]string, error) {
+	if db == nil || db.DB == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	paths := []string{}
+	err := db.DB.Transaction(func(tx *gorm.DB) error {
+		routes, err := loadRoutes(tx)
+		if err != nil {
+			return err
+		}
+
+		for _, route := range routes {
+			if match(route) {
+				paths = append(paths, route.Path)
+			}
+		}
+		if len(paths) == 0 {
+			return nil
+		}
+
+		switch action {
+		case BulkEnable, BulkDisable:
+			return tx.Model(&config.Route{}).Where("path IN ?", paths).Updates(map[string]interface{}{
+				"is_active": action == BulkEnable,
+				"version":   gorm.Expr("version + 1"),
+			}).Error
+		case BulkDelete:
+			return tx.Where("path IN ?", paths).Delete(&config.Route{}).Error
+		default:
+			return fmt.Errorf("unknown bulk action: %s", action)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply bulk %s: %w", action, err)
+	}
+
+	return paths, nil
+}
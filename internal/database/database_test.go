@@ -0,0 +1,65 @@
+package database
+
+import (
+	"context"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"path/filepath"
+	"testing"
+)
+
+func openTestDatabase(t *testing.T) *Database {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "routes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestCountActiveRoutes(t *testing.T) {
+	db := openTestDatabase(t)
+
+	count, err := db.CountActiveRoutes(context.Background())
+	if err != nil || count != 0 {
+		t.Fatalf("empty database: got %d, %v; want 0", count, err)
+	}
+
+	for _, route := range []*config.Route{
+		{Path: "/users", ServiceURL: "http://users.internal", Methods: []string{"GET"}, IsActive: true},
+		{Path: "/orders", ServiceURL: "http://orders.internal", Methods: []string{"GET"}, IsActive: true},
+		{Path: "/legacy", ServiceURL: "http://legacy.internal", Methods: []string{"GET"}, IsActive: false},
+	} {
+		if err := db.AddRoute(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err = db.CountActiveRoutes(context.Background())
+	if err != nil || count != 2 {
+		t.Fatalf("got %d, %v; want the 2 active routes", count, err)
+	}
+
+	if err := db.DeleteRoute("/users"); err != nil {
+		t.Fatal(err)
+	}
+	if count, _ = db.CountActiveRoutes(context.Background()); count != 1 {
+		t.Fatalf("after delete: got %d, want 1", count)
+	}
+}
+
+func TestCountActiveRoutesCanceled(t *testing.T) {
+	db := openTestDatabase(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.CountActiveRoutes(ctx); err == nil {
+		t.Fatal("count with a canceled context succeeded")
+	}
+}
+
+func TestCountActiveRoutesUninitialized(t *testing.T) {
+	var db *Database
+	if _, err := db.CountActiveRoutes(context.Background()); err == nil {
+		t.Fatal("count on a nil database succeeded")
+	}
+}
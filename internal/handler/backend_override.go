@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/internal/auth"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// backendOverrideHeader pins a request to one backend of the route, bypassing
+// the balancer, to debug multi-backend routes (e.g. a canary instance).
+const backendOverrideHeader = "X-Gateway-Backend"
+
+// backendOverride returns the backend named in X-Gateway-Backend, or "" when
+// the header wasn't sent. The header is only honoured from the trusted proxy
+// networks or with a gateway admin token; otherwise, or when it names a
+// backend the route doesn't have, the error response is written and ok is
+// false.
+func (h *Handler) backendOverride(w http.ResponseWriter, r *http.Request, route *config.Route) (backend string, ok bool) {
+	backend = strings.TrimSpace(r.Header.Get(backendOverrideHeader))
+	if backend == "" {
+		return "", true
+	}
+	// O backend não precisa saber que a instância foi escolhida à mão
+	r.Header.Del(backendOverrideHeader)
+
+	requestID := r.Header.Get(h.cfg.Server.RequestIDHeader)
+	if !h.trustedForOverride(r) {
+		h.logger.Warn("Rejected backend override from untrusted client",
+			zap.String("requestID", requestID),
+			zap.String("path", route.Path),
+			zap.String("remoteAddr", r.RemoteAddr))
+		h.writeError(w, r, http.StatusForbidden, backendOverrideHeader+" is not allowed for this client")
+		return "", false
+	}
+
+	for _, upstream := range route.Backends() {
+		if upstream.URL == backend {
+			h.logger.Info("Backend override",
+				zap.String("requestID", requestID),
+				zap.String("path", route.Path),
+				zap.String("backend", backend))
+			return backend, true
+		}
+	}
+	h.writeError(w, r, http.StatusBadRequest, backendOverrideHeader+" does not name a backend of this route")
+	return "", false
+}
+
+// trustedForOverride checks the direct peer against the trusted proxy
+// networks, then the Authorization header for a gateway admin token.
+func (h *Handler) trustedForOverride(r *http.Request) bool {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil && auth.IsTrustedSource(host, h.trustedNets) {
+		return true
+	}
+	scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	_, err := auth.ParseGatewayToken(strings.TrimSpace(token))
+	return err == nil
+}
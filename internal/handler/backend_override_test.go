@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/internal/auth"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newNamedBackend answers with its name, or "leaked" if the override header
+// reached it.
+func newNamedBackend(t *testing.T, name string) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(backendOverrideHeader) != "" {
+			w.Write([]byte("leaked"))
+			return
+		}
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func TestBackendOverride(t *testing.T) {
+	stable := newNamedBackend(t, "stable")
+	canary := newNamedBackend(t, "canary")
+	route := newTestRoute("/items", stable.URL)
+	route.Upstreams = []config.Upstream{{URL: stable.URL, Weight: 9}, {URL: canary.URL}}
+
+	adminToken, err := auth.GenerateJWT("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		trustedCIDRs  []string
+		authorization string
+		backend       string
+		status        int
+		body          string
+	}{
+		{"untrusted client", nil, "", canary.URL, http.StatusForbidden, ""},
+		{"invalid token", nil, "Bearer not-a-token", canary.URL, http.StatusForbidden, ""},
+		{"admin token", nil, "Bearer " + adminToken, canary.URL, http.StatusOK, "canary"},
+		{"trusted proxy", []string{"127.0.0.0/8"}, "", canary.URL, http.StatusOK, "canary"},
+		{"unknown backend", nil, "Bearer " + adminToken, "http://elsewhere.internal", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			cfg.Auth.TrustedProxyCIDRs = tt.trustedCIDRs
+			core, logs := observer.New(zap.InfoLevel)
+			p := newTestProxyWithLogger(t, zap.New(core), cfg, route)
+
+			// Com peso 9 para 1, só a fixação explica várias respostas seguidas do canary
+			for i := 0; i < 3; i++ {
+				req := newRequest(t, http.MethodGet, "/items", nil)
+				req.Header.Set(backendOverrideHeader, tt.backend)
+				if tt.authorization != "" {
+					req.Header.Set("Authorization", tt.authorization)
+				}
+				resp, body := p.do(t, req)
+				if resp.StatusCode != tt.status {
+					t.Fatalf("request %d: status %d (%s), want %d", i+1, resp.StatusCode, body, tt.status)
+				}
+				if tt.status == http.StatusOK && body != tt.body {
+					t.Fatalf("request %d: served by %q, want %q", i+1, body, tt.body)
+				}
+			}
+
+			overrides := logs.FilterMessage("Backend override").All()
+			if tt.status != http.StatusOK {
+				if len(overrides) != 0 {
+					t.Fatalf("%d overrides logged for rejected requests", len(overrides))
+				}
+				return
+			}
+			if len(overrides) != 3 {
+				t.Fatalf("%d overrides logged, want 3", len(overrides))
+			}
+			if got := overrides[0].ContextMap()["backend"]; got != tt.backend {
+				t.Fatalf("override logged for backend %v, want %s", got, tt.backend)
+			}
+		})
+	}
+}
+
+func TestBackendOverrideAbsentKeepsBalancing(t *testing.T) {
+	a := newNamedBackend(t, "a")
+	b := newNamedBackend(t, "b")
+	route := newTestRoute("/items", a.URL)
+	route.Upstreams = []config.Upstream{{URL: a.URL}, {URL: b.URL}}
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	if counts := p.hits(t, "/items", 4); counts["a"] != 2 || counts["b"] != 2 {
+		t.Fatalf("answers %v, want 2 from each backend", counts)
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"slices"
+	"sync"
+)
+
+// balancer picks the backend of each request with smooth weighted
+// round-robin, so an upstream with weight 3 gets 3 of every 4 requests
+// interleaved with the others instead of in bursts.
+type balancer struct {
+	mu     sync.Mutex
+	states map[string]*balancerState
+}
+
+// balancerState is the round-robin position of one route. upstreams is the
+// list it was built for; a route update with different upstreams resets it.
+type balancerState struct {
+	upstreams []config.Upstream
+	current   []int
+}
+
+func newBalancer() *balancer {
+	return &balancer{states: make(map[string]*balancerState)}
+}
+
+// next returns the URL of the backend that serves the next request to route,
+// skipping the ones ejected reports as out of rotation. If every upstream is
+// ejected all of them are used, since refusing the traffic would be worse.
+func (b *balancer) next(route *config.Route, ejected func(backend string) bool) string {
+	backends := route.Backends()
+	if len(backends) == 1 {
+		return backends[0].URL
+	}
+
+	available := make([]bool, len(backends))
+	anyAvailable := false
+	for i, upstream := range backends {
+		available[i] = !ejected(upstream.URL)
+		anyAvailable = anyAvailable || available[i]
+	}
+	if !anyAvailable {
+		for i := range available {
+			available[i] = true
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state, ok := b.states[route.Path]
+	if !ok || !slices.Equal(state.upstreams, backends) {
+		state = &balancerState{upstreams: slices.Clone(backends), current: make([]int, len(backends))}
+		b.states[route.Path] = state
+	}
+
+	// Cada upstream ganha seu peso; o maior acumulado é escolhido e perde o total
+	total, best := 0, -1
+	for i, upstream := range backends {
+		if !available[i] {
+			continue
+		}
+		weight := upstream.EffectiveWeight()
+		state.current[i] += weight
+		total += weight
+		if best < 0 || state.current[i] > state.current[best] {
+			best = i
+		}
+	}
+	state.current[best] -= total
+	return backends[best].URL
+}
+
+func (b *balancer) reset() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	cleared := len(b.states)
+	b.states = make(map[string]*balancerState)
+	return cleared
+}
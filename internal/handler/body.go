@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// bufferBody reads the request body into memory so it can be replayed by
+// mirroring and similar features, rewiring r.Body and r.GetBody to the buffer.
+// Bodies larger than limit are left streaming (intact) and buffered is false.
+func bufferBody(r *http.Request, limit int64) (body []byte, buffered bool, err error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+	if r.ContentLength > limit {
+		return nil, false, nil
+	}
+
+	original := r.Body
+	body, err = io.ReadAll(io.LimitReader(original, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Passou do limite: devolve o que já foi lido na frente do restante do stream
+	if int64(len(body)) > limit {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), original), original}
+		return nil, false, nil
+	}
+
+	original.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	r.ContentLength = int64(len(body))
+	return body, true, nil
+}
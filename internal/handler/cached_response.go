@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+)
+
+// uncacheableHeaders are per-connection or per-client response headers that
+// are never stored in a CachedResponse.
+var uncacheableHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Connection",
+	"Set-Cookie",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// CachedResponse is a backend response stored to be replayed to other
+// clients: the status, the cacheable headers and the full body.
+type CachedResponse struct {
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     []byte      `json:"body"`
+	StoredAt time.Time   `json:"storedAt"`
+}
+
+// newCachedResponse copies a backend response for replay. header must hold
+// only the backend's headers, not the ones the gateway added for the original
+// client. It returns nil when the response sets cookies, since it was
+// personalized for that client.
+func newCachedResponse(status int, header http.Header, body []byte) *CachedResponse {
+	if header.Get("Set-Cookie") != "" {
+		return nil
+	}
+
+	stored := header.Clone()
+	for _, name := range uncacheableHeaders {
+		stored.Del(name)
+	}
+	return &CachedResponse{Status: status, Header: stored, Body: body, StoredAt: time.Now()}
+}
+
+// writeTo replays the response. Headers the gateway already set for this
+// client (request ID, CORS, ...) are kept instead of the stored ones.
+func (c *CachedResponse) writeTo(w http.ResponseWriter) {
+	dst := w.Header()
+	for key, values := range c.Header {
+		if _, exists := dst[key]; exists {
+			continue
+		}
+		dst[key] = append([]string(nil), values...)
+	}
+	w.WriteHeader(c.Status)
+	w.Write(c.Body)
+}
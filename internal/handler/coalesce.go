@@ -0,0 +1,140 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// flightCall is an in-flight coalesced call. resp is nil when the leader's
+// response can't be shared (too large, per-client or failed).
+type flightCall struct {
+	done chan struct{}
+	resp *CachedResponse
+}
+
+// coalescer lets concurrent identical requests wait for a single backend call
+// and reuse its response, in the spirit of singleflight.
+type coalescer struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{calls: make(map[string]*flightCall)}
+}
+
+// do runs serve for the first request with a given key. Requests arriving
+// while it is in flight wait and receive the captured response; if it could
+// not be captured they fall back to calling serve themselves.
+func (g *coalescer) do(key string, w http.ResponseWriter, limit int64, serve func(http.ResponseWriter)) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		<-call.done
+		if call.resp == nil {
+			serve(w)
+			return
+		}
+		call.resp.writeTo(w)
+		return
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	// O líder responde direto ao seu cliente enquanto guarda uma cópia limitada
+	tee := &teeResponseWriter{ResponseWriter: w, limit: limit, status: http.StatusOK}
+	defer func() {
+		call.resp = tee.shared()
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(call.done)
+	}()
+
+	serve(tee)
+}
+
+// coalesceKey identifies identical requests. The credentials are part of the
+// key so a response is only shared between requests of the same client.
+func coalesceKey(r *http.Request) string {
+	return strings.Join([]string{
+		r.Method,
+		r.URL.RequestURI(),
+		r.Header.Get("Accept"),
+		r.Header.Get("Accept-Encoding"),
+		r.Header.Get("Authorization"),
+		strings.Join(r.Header.Values("Cookie"), "; "),
+	}, "\n")
+}
+
+// backendHeaderRecorder is implemented by the writers that keep a response to
+// replay to other clients. The proxy hands them the backend's own headers, so
+// the ones the gateway added for the current client (request ID, CORS, ...)
+// are never replayed.
+type backendHeaderRecorder interface {
+	recordBackendHeader(header http.Header)
+}
+
+// teeResponseWriter writes the response through while keeping a copy, up to
+// limit bytes, with the backend's headers.
+type teeResponseWriter struct {
+	http.ResponseWriter
+	limit       int64
+	status      int
+	wroteHeader bool
+	header      http.Header
+	buf         bytes.Buffer
+	overflow    bool
+}
+
+func (t *teeResponseWriter) recordBackendHeader(header http.Header) {
+	t.header = header.Clone()
+	// Cache e agrupamento podem estar encadeados; os dois precisam dos headers
+	if next, ok := t.ResponseWriter.(backendHeaderRecorder); ok {
+		next.recordBackendHeader(header)
+	}
+}
+
+func (t *teeResponseWriter) WriteHeader(status int) {
+	t.status = status
+	t.wroteHeader = true
+	t.ResponseWriter.WriteHeader(status)
+}
+
+func (t *teeResponseWriter) Write(b []byte) (int, error) {
+	if !t.wroteHeader {
+		t.WriteHeader(http.StatusOK)
+	}
+	if !t.overflow {
+		if int64(t.buf.Len()+len(b)) > t.limit {
+			t.overflow = true
+			t.buf.Reset()
+		} else {
+			t.buf.Write(b)
+		}
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *teeResponseWriter) Flush() {
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// shared returns the captured response, or nil if it can't be reused: too
+// large, not from the backend, or not successful.
+func (t *teeResponseWriter) shared() *CachedResponse {
+	if t.overflow || !t.wroteHeader || t.header == nil {
+		return nil
+	}
+	// Erros do líder (cancelamento, timeout, backend fora) não são repassados aos demais
+	if t.status < http.StatusOK || t.status >= http.StatusBadRequest {
+		return nil
+	}
+	return newCachedResponse(t.status, t.header, t.buf.Bytes())
+}
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// coalesceBackend counts its calls and holds each one until release is
+// closed, so concurrent requests overlap.
+func coalesceBackend(t *testing.T) (backend *httptest.Server, calls *atomic.Int32, release chan struct{}) {
+	t.Helper()
+	calls = &atomic.Int32{}
+	release = make(chan struct{})
+	backend = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		fmt.Fprintf(w, "cookie=%s", r.Header.Get("Cookie"))
+	}))
+	t.Cleanup(backend.Close)
+	return backend, calls, release
+}
+
+// concurrentGets sends n GETs at once, each with the cookie chosen by
+// cookieFor, releases the backend once they are all waiting and returns the
+// response bodies.
+func concurrentGets(t *testing.T, p *testProxy, path string, n int, cookieFor func(i int) string, release chan struct{}) []string {
+	t.Helper()
+	bodies := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := newRequest(t, http.MethodGet, path, nil)
+			if cookie := cookieFor(i); cookie != "" {
+				req.Header.Set("Cookie", cookie)
+			}
+			resp, body := p.do(t, req)
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("request %d: status %d", i, resp.StatusCode)
+			}
+			bodies[i] = body
+		}(i)
+	}
+	// Dá tempo para todas as requisições chegarem ao gateway antes de o backend responder
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	return bodies
+}
+
+func TestCoalesceSharesOneBackendCall(t *testing.T) {
+	backend, calls, release := coalesceBackend(t)
+	route := newTestRoute("/items", backend.URL)
+	route.Coalesce = true
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	concurrentGets(t, p, "/items", 10, func(int) string { return "" }, release)
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("backend called %d times for 10 concurrent GETs, want 1", n)
+	}
+}
+
+func TestCoalesceKeepsCookieSessionsApart(t *testing.T) {
+	backend, calls, release := coalesceBackend(t)
+	route := newTestRoute("/me", backend.URL)
+	route.Coalesce = true
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	cookieFor := func(i int) string { return fmt.Sprintf("session=user%d", i%2) }
+	bodies := concurrentGets(t, p, "/me", 6, cookieFor, release)
+
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("backend called %d times for 2 sessions, want 2", n)
+	}
+	for i, body := range bodies {
+		if want := "cookie=" + cookieFor(i); body != want {
+			t.Fatalf("request %d got %q, want %q", i, body, want)
+		}
+	}
+}
+
+func TestCoalesceSkipsStreamingRoutes(t *testing.T) {
+	backend, calls, release := coalesceBackend(t)
+	route := newTestRoute("/events", backend.URL)
+	route.Coalesce = true
+	route.Streaming = true
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	concurrentGets(t, p, "/events", 3, func(int) string { return "" }, release)
+
+	if n := calls.Load(); n != 3 {
+		t.Fatalf("backend called %d times for 3 streaming GETs, want 3", n)
+	}
+}
+
+func TestCoalesceFollowerSurvivesCancelledLeader(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Só a primeira chamada (a do líder) fica presa
+		if calls.Add(1) == 1 {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Write([]byte("fresh"))
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	route := newTestRoute("/items", backend.URL)
+	route.Coalesce = true
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	leader := newRequest(t, http.MethodGet, p.server.URL+"/items", nil).WithContext(ctx)
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		if resp, err := testClient.Do(leader); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	type result struct {
+		status int
+		body   string
+	}
+	follower := make(chan result, 1)
+	go func() {
+		resp, body := p.do(t, newRequest(t, http.MethodGet, "/items", nil))
+		follower <- result{resp.StatusCode, body}
+	}()
+	// Dá tempo para o seguidor entrar na espera pelo líder antes do cancelamento
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	<-leaderDone
+
+	select {
+	case got := <-follower:
+		if got.status != http.StatusOK || got.body != "fresh" {
+			t.Fatalf("follower got %d %q, want the backend's 200 \"fresh\"", got.status, got.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("follower never answered")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("backend called %d times, want the follower to retry once", n)
+	}
+}
+
+// newClientHeaderServer serves p's handler behind a stand-in for the gateway
+// middlewares that add per-client headers: the request ID and, for requests
+// with an Origin, CORS.
+func newClientHeaderServer(t *testing.T, p *testProxy) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		p.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// getWithHeaders sends a GET to url with the given request headers and
+// returns the response headers and body.
+func getWithHeaders(t *testing.T, url string, header map[string]string) (http.Header, string) {
+	t.Helper()
+	req := newRequest(t, http.MethodGet, url, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	resp, err := testClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return nil, ""
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+	return resp.Header, string(body)
+}
+
+func TestCoalesceReplaysOnlyBackendHeaders(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-release
+		w.Header().Set("X-Backend", "items")
+		w.Write([]byte("items"))
+	}))
+	defer backend.Close()
+
+	route := newTestRoute("/items", backend.URL)
+	route.Coalesce = true
+	server := newClientHeaderServer(t, newTestProxy(t, newTestConfig(t), route))
+
+	var leader http.Header
+	leaderDone := make(chan struct{})
+	go func() {
+		defer close(leaderDone)
+		leader, _ = getWithHeaders(t, server.URL+"/items", map[string]string{"X-Request-Id": "leader", "Origin": "https://app.example.com"})
+	}()
+	for calls.Load() == 0 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var follower http.Header
+	var body string
+	followerDone := make(chan struct{})
+	go func() {
+		defer close(followerDone)
+		follower, body = getWithHeaders(t, server.URL+"/items", map[string]string{"X-Request-Id": "follower"})
+	}()
+	// Dá tempo para o seguidor entrar na espera pelo líder
+	time.Sleep(200 * time.Millisecond)
+	close(release)
+	<-leaderDone
+	<-followerDone
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("backend called %d times, want the follower to share the leader's call", n)
+	}
+	if got := leader.Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("leader Access-Control-Allow-Origin %q, want its own origin", got)
+	}
+	if body != "items" || follower.Get("X-Backend") != "items" {
+		t.Fatalf("follower got body %q and X-Backend %q, want the backend's", body, follower.Get("X-Backend"))
+	}
+	if got := follower.Get("X-Request-Id"); got != "follower" {
+		t.Fatalf("follower X-Request-Id %q, want its own", got)
+	}
+	for _, name := range []string{"Access-Control-Allow-Origin", "Vary"} {
+		if got := follower.Values(name); len(got) != 0 {
+			t.Fatalf("follower got the leader's %s %v", name, got)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BackendHealth is the last outcome observed when talking to a backend.
+type BackendHealth struct {
+	LastStatus int       `json:"lastStatus,omitempty"`
+	LastError  string    `json:"lastError,omitempty"`
+	LastSeen   time.Time `json:"lastSeen"`
+}
+
+type Dependency struct {
+	Role     string         `json:"role"`
+	URL      string         `json:"url"`
+	Percent  int            `json:"percent,omitempty"`
+	Weight   int            `json:"weight,omitempty"`
+	InFlight int64          `json:"inFlight"`
+	Health   *BackendHealth `json:"health,omitempty"`
+	// Ejected indica uma instância fora do balanceamento pela detecção de outliers
+	Ejected bool `json:"ejected,omitempty"`
+}
+
+type backendTracker struct {
+	mu       sync.RWMutex
+	backends map[string]*BackendHealth
+}
+
+func newBackendTracker() *backendTracker {
+	return &backendTracker{backends: make(map[string]*BackendHealth)}
+}
+
+func (t *backendTracker) recordStatus(backend string, status int) {
+	t.record(backend, BackendHealth{LastStatus: status, LastSeen: time.Now()})
+}
+
+func (t *backendTracker) recordError(backend string, err error) {
+	t.record(backend, BackendHealth{LastError: err.Error(), LastSeen: time.Now()})
+}
+
+func (t *backendTracker) record(backend string, health BackendHealth) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.backends[backend] = &health
+}
+
+func (t *backendTracker) get(backend string) *BackendHealth {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	health, ok := t.backends[backend]
+	if !ok {
+		return nil
+	}
+	copied := *health
+	return &copied
+}
+
+// GetRouteDependencies lists every backend a route talks to together with the
+// last health observed for each one.
+func (h *Handler) GetRouteDependencies(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Path query parameter required"})
+		return
+	}
+
+	route, exists := h.routes.Get(path)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
+
+	var dependencies []Dependency
+	if len(route.Upstreams) == 0 {
+		dependencies = append(dependencies, Dependency{
+			Role:     "primary",
+			URL:      route.ServiceURL,
+			InFlight: h.inFlight.get(route.ServiceURL),
+			Health:   h.backends.get(route.ServiceURL),
+		})
+	}
+	// Cada instância balanceada tem sua própria saúde, então uma instância fora não esconde as outras
+	for _, upstream := range route.Upstreams {
+		dependencies = append(dependencies, Dependency{
+			Role:     "upstream",
+			URL:      upstream.URL,
+			Weight:   upstream.EffectiveWeight(),
+			InFlight: h.inFlight.get(upstream.URL),
+			Health:   h.backends.get(upstream.URL),
+			Ejected:  h.outliers.isEjected(upstream.URL),
+		})
+	}
+	if route.MirrorURL != "" {
+		percent := route.MirrorPercent
+		if percent == 0 {
+			percent = 100
+		}
+		dependencies = append(dependencies, Dependency{
+			Role:     "mirror",
+			URL:      route.MirrorURL,
+			Percent:  percent,
+			InFlight: h.inFlight.get(route.MirrorURL),
+			Health:   h.backends.get(route.MirrorURL),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"path":         route.Path,
+		"isActive":     route.IsActive,
+		"dependencies": dependencies,
+	})
+}
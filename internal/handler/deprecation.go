@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+)
+
+// pathCounter counts events per route path.
+type pathCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newPathCounter() *pathCounter {
+	return &pathCounter{counts: make(map[string]int64)}
+}
+
+func (p *pathCounter) inc(path string) {
+	p.mu.Lock()
+	p.counts[path]++
+	p.mu.Unlock()
+}
+
+func (p *pathCounter) get(path string) int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts[path]
+}
+
+// markDeprecated announces a deprecated route through the Deprecation and
+// Sunset headers and records who is still calling it.
+func (h *Handler) markDeprecated(w http.ResponseWriter, r *http.Request, route *config.Route) {
+	w.Header().Set("Deprecation", "true")
+	if route.SunsetAt != nil {
+		w.Header().Set("Sunset", route.SunsetAt.UTC().Format(http.TimeFormat))
+	}
+
+	h.deprecatedCalls.inc(route.Path)
+	h.logger.Info("Deprecated route used",
+		zap.String("path", route.Path),
+		zap.String("clientIP", r.RemoteAddr),
+		zap.String("userAgent", r.UserAgent()))
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeprecatedRoute(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	sunset := time.Date(2027, time.March, 1, 0, 0, 0, 0, time.UTC)
+	legacy := newTestRoute("/v1/users", backend.URL)
+	legacy.Deprecated = true
+	legacy.SunsetAt = &sunset
+	noSunset := newTestRoute("/v1/orders", backend.URL)
+	noSunset.Deprecated = true
+	current := newTestRoute("/v2/users", backend.URL)
+	p := newTestProxy(t, newTestConfig(t), legacy, noSunset, current)
+
+	for i := 0; i < 2; i++ {
+		resp, _ := p.get(t, "/v1/users")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("/v1/users: status %d, want the deprecated route still served", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Deprecation"); got != "true" {
+			t.Fatalf("/v1/users: Deprecation %q, want true", got)
+		}
+		if got, want := resp.Header.Get("Sunset"), "Mon, 01 Mar 2027 00:00:00 GMT"; got != want {
+			t.Fatalf("/v1/users: Sunset %q, want %q", got, want)
+		}
+	}
+
+	resp, _ := p.get(t, "/v1/orders")
+	if resp.Header.Get("Deprecation") != "true" || resp.Header.Get("Sunset") != "" {
+		t.Fatalf("/v1/orders: Deprecation %q, Sunset %q; want only Deprecation",
+			resp.Header.Get("Deprecation"), resp.Header.Get("Sunset"))
+	}
+
+	resp, _ = p.get(t, "/v2/users")
+	if resp.Header.Get("Deprecation") != "" || resp.Header.Get("Sunset") != "" {
+		t.Fatal("/v2/users: deprecation headers on a current route")
+	}
+
+	for path, want := range map[string]int64{"/v1/users": 2, "/v1/orders": 1, "/v2/users": 0} {
+		var metrics RouteMetrics
+		_, body := p.get(t, "/admin/metrics?path="+path)
+		if err := json.Unmarshal([]byte(body), &metrics); err != nil {
+			t.Fatal(err)
+		}
+		if metrics.DeprecatedCalls != want {
+			t.Fatalf("%s: deprecatedCalls %d, want %d", path, metrics.DeprecatedCalls, want)
+		}
+	}
+}
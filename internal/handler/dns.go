@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type DNSCacheStats struct {
+	Enabled bool          `json:"enabled"`
+	TTL     time.Duration `json:"ttl"`
+	Entries int           `json:"entries"`
+	Hits    int64         `json:"hits"`
+	Misses  int64         `json:"misses"`
+}
+
+type dnsEntry struct {
+	addrs   []string
+	expires time.Time
+}
+
+// dnsCache resolves backend hosts through a resolver with a lookup timeout and
+// keeps the answers for a short TTL, so each new connection doesn't pay for a
+// DNS round trip. With a TTL of zero the transports use the standard dialer
+// instead (see dialFunc).
+type dnsCache struct {
+	resolver      *net.Resolver
+	dialer        *net.Dialer
+	ttl           time.Duration
+	lookupTimeout time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]dnsEntry
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func newDNSCache(ttl, lookupTimeout, dialTimeout time.Duration) *dnsCache {
+	return &dnsCache{
+		resolver:      net.DefaultResolver,
+		dialer:        &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second},
+		ttl:           ttl,
+		lookupTimeout: lookupTimeout,
+		entries:       make(map[string]dnsEntry),
+	}
+}
+
+func (d *dnsCache) lookup(ctx context.Context, host string) ([]string, error) {
+	if d.ttl > 0 {
+		d.mu.RLock()
+		entry, ok := d.entries[host]
+		d.mu.RUnlock()
+		if ok && time.Now().Before(entry.expires) {
+			d.hits.Add(1)
+			return entry.addrs, nil
+		}
+		d.misses.Add(1)
+	}
+
+	if d.lookupTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.lookupTimeout)
+		defer cancel()
+	}
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.ttl > 0 {
+		d.mu.Lock()
+		d.entries[host] = dnsEntry{addrs: addrs, expires: time.Now().Add(d.ttl)}
+		d.mu.Unlock()
+	}
+	return addrs, nil
+}
+
+// dialFunc returns the dialer for the backend transports: the caching
+// DialContext when the cache is enabled, otherwise the standard dialer, which
+// keeps Happy Eyeballs and the dual-stack fallback.
+func (d *dnsCache) dialFunc() func(ctx context.Context, network, address string) (net.Conn, error) {
+	if d.ttl > 0 {
+		return d.DialContext
+	}
+	return d.dialer.DialContext
+}
+
+// DialContext is used as the transport dialer when the cache is enabled,
+// trying each resolved address in turn until one connects.
+func (d *dnsCache) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialErr error
+	for _, addr := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if dialErr == nil {
+		dialErr = errors.New("no addresses resolved for " + host)
+	}
+	return nil, dialErr
+}
+
+func (d *dnsCache) reset() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cleared := len(d.entries)
+	d.entries = make(map[string]dnsEntry)
+	return cleared
+}
+
+func (d *dnsCache) stats() DNSCacheStats {
+	d.mu.RLock()
+	entries := len(d.entries)
+	d.mu.RUnlock()
+
+	return DNSCacheStats{
+		Enabled: d.ttl > 0,
+		TTL:     d.ttl,
+		Entries: entries,
+		Hits:    d.hits.Load(),
+		Misses:  d.misses.Load(),
+	}
+}
+
+// GetDNSStats reports the backend DNS cache hit and miss counters.
+func (h *Handler) GetDNSStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.dns.stats())
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDNSCacheOnlyWhenEnabled(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Fecha a conexão para que cada requisição disque de novo
+		w.Header().Set("Connection", "close")
+	}))
+	defer backend.Close()
+	backendURL := strings.Replace(backend.URL, "127.0.0.1", "localhost", 1)
+
+	for _, tt := range []struct {
+		ttl                time.Duration
+		wantHits, wantMiss int64
+	}{
+		{0, 0, 0},
+		{time.Minute, 2, 1},
+	} {
+		cfg := newTestConfig(t)
+		cfg.Proxy.DNSCacheTTL = tt.ttl
+		p := newTestProxy(t, cfg, newTestRoute("/users", backendURL))
+
+		for i := 0; i < 3; i++ {
+			if resp, _ := p.get(t, "/users"); resp.StatusCode != http.StatusOK {
+				t.Fatalf("ttl %v: status %d", tt.ttl, resp.StatusCode)
+			}
+		}
+		if stats := p.dns.stats(); stats.Hits != tt.wantHits || stats.Misses != tt.wantMiss {
+			t.Fatalf("ttl %v: %d hits and %d misses, want %d and %d", tt.ttl, stats.Hits, stats.Misses, tt.wantHits, tt.wantMiss)
+		}
+	}
+}
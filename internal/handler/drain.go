@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"context"
+	"sync"
+)
+
+// drainer tracks the proxied requests in flight so shutdown can stop taking
+// new ones and wait for the active ones, cancelling them if they take too long.
+type drainer struct {
+	mu       sync.Mutex
+	active   int
+	draining bool
+	idle     chan struct{}
+
+	closed     context.Context
+	forceClose context.CancelFunc
+}
+
+func newDrainer() *drainer {
+	closed, forceClose := context.WithCancel(context.Background())
+	return &drainer{idle: make(chan struct{}), closed: closed, forceClose: forceClose}
+}
+
+// acquire registers a new request, returning false once draining started.
+func (d *drainer) acquire() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return false
+	}
+	d.active++
+	return true
+}
+
+func (d *drainer) release() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.active--
+	if d.draining && d.active == 0 {
+		close(d.idle)
+	}
+}
+
+func (d *drainer) start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.draining {
+		return
+	}
+	d.draining = true
+	if d.active == 0 {
+		close(d.idle)
+	}
+}
+
+// bind returns a context for an upstream call that is also cancelled when the
+// drain is forced.
+func (d *drainer) bind(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(d.closed, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// Drain stops accepting proxied requests and waits for the active ones to
+// finish. If ctx expires first, the remaining upstream calls are cancelled and
+// ctx's error is returned.
+func (h *Handler) Drain(ctx context.Context) error {
+	h.drain.start()
+
+	select {
+	case <-h.drain.idle:
+		return nil
+	case <-ctx.Done():
+		h.drain.forceClose()
+		return ctx.Err()
+	}
+}
+
+// ActiveProxyRequests returns the number of proxied requests in flight.
+func (h *Handler) ActiveProxyRequests() int {
+	h.drain.mu.Lock()
+	defer h.drain.mu.Unlock()
+	return h.drain.active
+}
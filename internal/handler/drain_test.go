@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newSlowBackend answers only once release is closed or the request is
+// cancelled.
+func newSlowBackend(t *testing.T, release <-chan struct{}) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+			w.Write([]byte("done"))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// startRequest sends a GET in the background and waits until the proxy
+// counts it as active.
+func (p *testProxy) startRequest(t *testing.T, path string) <-chan int {
+	t.Helper()
+	status := make(chan int, 1)
+	go func() {
+		resp, err := testClient.Get(p.server.URL + path)
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for p.ActiveProxyRequests() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("request never reached the proxy")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return status
+}
+
+func (p *testProxy) draining() bool {
+	p.drain.mu.Lock()
+	defer p.drain.mu.Unlock()
+	return p.drain.draining
+}
+
+func TestDrainWaitsForSlowBackend(t *testing.T) {
+	release := make(chan struct{})
+	backend := newSlowBackend(t, release)
+	p := newTestProxy(t, newTestConfig(t), newTestRoute("/slow", backend.URL))
+
+	inflight := p.startRequest(t, "/slow")
+
+	drained := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		drained <- p.Drain(ctx)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !p.draining() {
+		if time.Now().After(deadline) {
+			t.Fatal("drain never started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Durante o drain novas chamadas são recusadas enquanto a ativa continua
+	if resp, _ := p.get(t, "/slow"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("new request during drain: status %d, want 503", resp.StatusCode)
+	}
+	select {
+	case err := <-drained:
+		t.Fatalf("drain returned %v with a request still active", err)
+	default:
+	}
+
+	close(release)
+	if status := <-inflight; status != http.StatusOK {
+		t.Fatalf("in-flight request: status %d, want it to complete with 200", status)
+	}
+	if err := <-drained; err != nil {
+		t.Fatalf("drain: %v", err)
+	}
+	if n := p.ActiveProxyRequests(); n != 0 {
+		t.Fatalf("%d requests still active after drain", n)
+	}
+}
+
+func TestDrainTimeoutCancelsUpstream(t *testing.T) {
+	backend := newSlowBackend(t, nil)
+	p := newTestProxy(t, newTestConfig(t), newTestRoute("/stuck", backend.URL))
+
+	inflight := p.startRequest(t, "/stuck")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("drain: %v, want the deadline to expire", err)
+	}
+
+	// O drain forçado cancela a chamada ao backend em vez de deixá-la pendurada
+	select {
+	case status := <-inflight:
+		if status != http.StatusBadGateway {
+			t.Fatalf("cancelled request: status %d, want 502", status)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight request not cancelled by the forced drain")
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// applyErrorPage replaces the backend response with the route's error page
+// for its status, if one is configured. It reports whether it did.
+func (h *Handler) applyErrorPage(resp *http.Response, route *config.Route) bool {
+	page := route.ErrorPageFor(resp.StatusCode)
+	if page == nil {
+		return false
+	}
+
+	// O body original do backend é descartado para não vazar detalhes internos
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	if page.ResponseStatus != 0 {
+		resp.StatusCode = page.ResponseStatus
+		resp.Status = strconv.Itoa(page.ResponseStatus) + " " + http.StatusText(page.ResponseStatus)
+	}
+	for _, name := range []string{"Content-Encoding", "Content-Range", "ETag", "Last-Modified"} {
+		resp.Header.Del(name)
+	}
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = "text/plain; charset=utf-8"
+	}
+	resp.Header.Set("Content-Type", contentType)
+	resp.Header.Set("Content-Length", strconv.Itoa(len(page.Body)))
+	resp.Body = io.NopCloser(bytes.NewReader([]byte(page.Body)))
+	resp.ContentLength = int64(len(page.Body))
+	resp.TransferEncoding = nil
+
+	h.errorPagesServed.inc(route.Path)
+	return true
+}
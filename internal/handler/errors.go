@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type acceptedType struct {
+	mediaType string
+	q         float64
+}
+
+// writeError writes a gateway-generated error in the media type negotiated
+// from the Accept header, falling back to the first configured type.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	contentType := negotiateContentType(r.Header.Get("Accept"), h.cfg.Proxy.ErrorContentTypes)
+
+	w.Header().Set("Content-Type", contentType+"; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	switch contentType {
+	case "text/html":
+		fmt.Fprintf(w, "<html><head><title>%d %s</title></head><body><h1>%d %s</h1><p>%s</p></body></html>",
+			status, http.StatusText(status), status, http.StatusText(status), html.EscapeString(message))
+	case "text/plain":
+		fmt.Fprintln(w, message)
+	default:
+		json.NewEncoder(w).Encode(map[string]string{"error": message})
+	}
+}
+
+func negotiateContentType(accept string, supported []string) string {
+	if len(supported) == 0 {
+		return "application/json"
+	}
+	if accept == "" {
+		return supported[0]
+	}
+
+	for _, accepted := range parseAccept(accept) {
+		for _, candidate := range supported {
+			if mediaTypeMatches(accepted.mediaType, candidate) {
+				return candidate
+			}
+		}
+	}
+	return supported[0]
+}
+
+func parseAccept(accept string) []acceptedType {
+	var types []acceptedType
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			types = append(types, acceptedType{mediaType: mediaType, q: q})
+		}
+	}
+
+	sort.SliceStable(types, func(i, j int) bool { return types[i].q > types[j].q })
+	return types
+}
+
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
@@ -1,20 +1,56 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"github.com/diillson/api-gateway-go/internal/auth"
 	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/internal/routestore"
 	"github.com/diillson/api-gateway-go/pkg/config"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Handler struct {
-	routes map[string]*config.Route
+	// routes é compartilhado com os middlewares
+	routes *routestore.Store
+
 	logger *zap.Logger
 	db     *database.Database
+	cfg    *config.Config
+
+	// trustedNets são os proxies autorizados a fixar o backend com X-Gateway-Backend
+	trustedNets []*net.IPNet
+
+	transport       *http.Transport
+	routeTransports map[string]*http.Transport
+	transportsMu    sync.Mutex
+
+	backends  *backendTracker
+	balancer  *balancer
+	outliers  *outlierDetector
+	coalescer *coalescer
+	responses *responseCache
+	schemas   *schemaValidator
+
+	deprecatedCalls  *pathCounter
+	errorPagesServed *pathCounter
+	mirrorsSucceeded *pathCounter
+	mirrorsFailed    *pathCounter
+	inFlight         *inFlightTracker
+	recorder         *recorder
+	dns              *dnsCache
+	drain            *drainer
+	latencies        *latencyTracker
+
+	warmed <-chan struct{}
 }
 
 type RouteMetrics struct {
@@ -22,84 +58,287 @@ type RouteMetrics struct {
 	TotalResponse time.Duration `json:"totalResponse"`
 	ServiceURL    string        `json:"serviceURL"`
 	Path          string        `json:"path"`
+
+	SchemaValidationFailed int64 `json:"schemaValidationFailed"`
+	DeprecatedCalls        int64 `json:"deprecatedCalls"`
+	ErrorPagesServed       int64 `json:"errorPagesServed"`
+	MirrorsSucceeded       int64 `json:"mirrorsSucceeded"`
+	MirrorsFailed          int64 `json:"mirrorsFailed"`
+	// RequestTimeout é o timeout aplicado hoje às chamadas ao backend (adaptativo ou fixo); 0 é sem timeout
+	RequestTimeout time.Duration `json:"requestTimeout"`
 }
 
-func NewHandler(db *database.Database, logger *zap.Logger) *Handler {
-	routes, err := db.GetRoutes()
+func NewHandler(db *database.Database, logger *zap.Logger, cfg *config.Config, routeStore *routestore.Store) *Handler {
+	routes := routeStore.All()
+	for _, route := range routes {
+		if len(route.Methods) == 0 {
+			logger.Warn("Route has no HTTP methods and will reject every request", zap.String("path", route.Path))
+		}
+	}
+
+	transport, err := newTransport(cfg.Proxy.CACertFile, cfg.Proxy.CACertPEM, cfg.Proxy.CAAppendSystem)
 	if err != nil {
-		logger.Error("Failed to load routes", zap.Error(err))
+		logger.Error("Failed to load proxy CA bundle, using system roots", zap.Error(err))
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	}
+	if transport.Proxy, err = proxyFunc(cfg.Proxy.UpstreamProxy, cfg.Proxy.NoProxy); err != nil {
+		logger.Error("Invalid upstream proxy, using proxy environment", zap.Error(err))
+		transport.Proxy = http.ProxyFromEnvironment
 	}
 
-	routeMap := make(map[string]*config.Route)
-	for _, route := range routes {
-		routeMap[route.Path] = route
+	h := &Handler{
+		routes:           routeStore,
+		logger:           logger,
+		db:               db,
+		cfg:              cfg,
+		trustedNets:      auth.ParseCIDRs(cfg.Auth.TrustedProxyCIDRs, logger),
+		transport:        transport,
+		routeTransports:  make(map[string]*http.Transport),
+		backends:         newBackendTracker(),
+		balancer:         newBalancer(),
+		outliers:         newOutlierDetector(cfg.Proxy.OutlierConsecutiveFailures, cfg.Proxy.OutlierEjectionTime),
+		coalescer:        newCoalescer(),
+		responses:        newResponseCache(cfg.Proxy.ResponseCacheMaxEntries),
+		schemas:          newSchemaValidator(),
+		deprecatedCalls:  newPathCounter(),
+		errorPagesServed: newPathCounter(),
+		mirrorsSucceeded: newPathCounter(),
+		mirrorsFailed:    newPathCounter(),
+		inFlight:         newInFlightTracker(),
+		recorder:         newRecorder(cfg.Record, logger),
+		drain:            newDrainer(),
+		dns:              newDNSCache(cfg.Proxy.DNSCacheTTL, cfg.Proxy.DNSLookupTimeout, cfg.Proxy.DialTimeout),
+		latencies:        newLatencyTracker(),
 	}
+	transport.DialContext = h.dns.dialFunc()
+	h.warmed = h.preconnect(routes)
 
-	return &Handler{routes: routeMap, logger: logger, db: db}
+	return h
+}
+
+// Warmed returns a channel closed when the backend preconnect started by
+// NewHandler has finished (immediately if preconnect is disabled).
+func (h *Handler) Warmed() <-chan struct{} {
+	return h.warmed
 }
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if err := h.updateRoutes(); err != nil {
-		h.logger.Error("Failed to update routes", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	// Durante o shutdown novas chamadas ao proxy são recusadas enquanto as ativas terminam
+	if !h.drain.acquire() {
+		w.Header().Set("Connection", "close")
+		h.writeError(w, r, http.StatusServiceUnavailable, "Service is shutting down")
 		return
 	}
+	defer h.drain.release()
+
+	ctx, cancel := h.drain.bind(r.Context())
+	defer cancel()
+	r = r.WithContext(ctx)
 
-	route, exists := h.routes[r.URL.Path]
-	if !exists || !route.IsMethodAllowed(r.Method) {
-		http.NotFound(w, r)
+	route, exists := h.routes.Lookup(r.URL.Path)
+	if !exists || !route.IsActive {
+		h.writeError(w, r, http.StatusNotFound, "Not Found")
 		return
 	}
+	if !route.IsMethodAllowed(r.Method) {
+		w.Header().Set("Allow", strings.Join(route.Methods, ", "))
+		h.writeError(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	if route.Deprecated {
+		h.markDeprecated(w, r, route)
+	}
 
-	// Parse the service URL
-	target, err := url.Parse(route.ServiceURL)
+	if route.RequireHTTPS && !isHTTPS(r) {
+		h.logger.Warn("Rejected plaintext request to HTTPS-only route", zap.String("path", route.Path))
+		h.writeError(w, r, http.StatusForbidden, "HTTPS required")
+		return
+	}
+
+	// Escolhe a instância do backend (round-robin ponderado quando a rota tem upstreams, sem as ejetadas),
+	// a não ser que um chamador confiável a tenha fixado com X-Gateway-Backend
+	pinned, ok := h.backendOverride(w, r, route)
+	if !ok {
+		return
+	}
+	backend := pinned
+	if backend == "" {
+		backend = h.balancer.next(route, h.outliers.isEjected)
+	}
+	balanced := len(route.Upstreams) > 1
+	target, err := url.Parse(backend)
 	if err != nil {
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		h.writeError(w, r, http.StatusInternalServerError, "Internal server error")
 		return
 	}
 
+	if route.RequestSchema != "" && !h.validateRequestSchema(w, r, route) {
+		return
+	}
+
+	// Em modo de teste, a troca é gravada antes que o proxy altere a requisição
+	if h.recorder.shouldRecord(route.Path) {
+		var finish func()
+		w, finish = h.recorder.capture(w, r, route)
+		defer finish()
+	}
+
+	// Espelha uma cópia da requisição antes que o proxy consuma o body
+	if shouldMirror(route) {
+		h.mirror(r, route)
+	}
+
+	if timeout := h.requestTimeout(route); timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+	start := time.Now()
+	recordLatency := func() {
+		if route.AdaptiveTimeout {
+			h.latencies.record(route.Path, time.Since(start))
+		}
+	}
+
 	// Create a new reverse proxy to forward the request to the service
 	proxy := httputil.NewSingleHostReverseProxy(target)
+	// Rotas de streaming repassam cada escrita do backend sem esperar o buffer encher
+	proxy.FlushInterval = h.cfg.Proxy.FlushInterval
+	if route.Streaming {
+		proxy.FlushInterval = -1
+	}
+	proxy.Transport = &countingTransport{next: h.transportFor(route), backend: backend, inFlight: h.inFlight}
+	// out é o writer passado ao proxy; se ele guarda a resposta para outros clientes, recebe os headers do backend
+	var out http.ResponseWriter
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		h.backends.recordStatus(backend, resp.StatusCode)
+		if balanced {
+			if resp.StatusCode >= http.StatusInternalServerError {
+				h.recordOutlierFailure(route, backend)
+			} else {
+				h.outliers.recordSuccess(backend)
+			}
+		}
+		recordLatency()
+		h.applyErrorPage(resp, route)
+		h.filterResponseHeaders(resp.Header, route)
+		if rec, ok := out.(backendHeaderRecorder); ok {
+			rec.recordBackendHeader(resp.Header)
+		}
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		h.backends.recordError(backend, err)
+		// Cancelamento pelo cliente (ou pelo drain) não diz nada sobre a saúde do backend
+		if balanced && !errors.Is(err, context.Canceled) {
+			h.recordOutlierFailure(route, backend)
+		}
+		h.logger.Error("Backend request failed",
+			zap.String("requestID", r.Header.Get(h.cfg.Server.RequestIDHeader)),
+			zap.String("path", route.Path),
+			zap.String("backend", backend),
+			zap.Error(err))
+		if errors.Is(err, context.DeadlineExceeded) {
+			// A chamada levou pelo menos o timeout; sem esta amostra o p99 nunca passaria dele
+			recordLatency()
+			h.writeError(w, r, http.StatusGatewayTimeout, "Gateway Timeout")
+			return
+		}
+		h.writeError(w, r, http.StatusBadGateway, "Bad Gateway")
+	}
 
 	// Modify the request
 	r.URL.Host = target.Host
 	r.URL.Scheme = target.Scheme
 	r.Header.Set("X-Forwarded-Host", r.Header.Get("Host"))
-	r.Host = target.Host
+	if !route.PreserveHostHeader {
+		r.Host = target.Host
+	}
+
+	serve := func(w http.ResponseWriter) {
+		out = w
+		proxy.ServeHTTP(w, r)
+	}
+
+	// Requisições idênticas e simultâneas (métodos cacheáveis) compartilham uma única chamada ao backend;
+	// em rotas de streaming os seguidores esperariam o stream inteiro, então elas não são agrupadas.
+	// Requisições com backend fixado nunca compartilham respostas com as balanceadas
+	if route.Coalesce && !route.Streaming && pinned == "" && config.MethodIn(r.Method, h.cfg.Proxy.CacheableMethods) {
+		serveOnce := serve
+		serve = func(w http.ResponseWriter) {
+			h.coalescer.do(coalesceKey(r), w, h.cfg.Proxy.CoalesceMaxBodyBytes, serveOnce)
+		}
+	}
+
+	// Respostas cacheáveis são servidas do cache sem chamar o backend enquanto o Cache-Control permitir
+	if route.CacheResponses && pinned == "" && config.MethodIn(r.Method, h.cfg.Proxy.CacheableMethods) {
+		h.serveCached(w, r, serve)
+		return
+	}
 
 	// Serve the request
-	proxy.ServeHTTP(w, r)
+	serve(w)
 }
 
-func (h *Handler) updateRoutes() error {
-	routes, err := h.db.GetRoutes()
-	if err != nil {
-		h.logger.Error("Failed to load routes", zap.Error(err))
-		return err
+func (h *Handler) recordOutlierFailure(route *config.Route, backend string) {
+	if h.outliers.recordFailure(backend) {
+		h.logger.Warn("Backend ejected after consecutive failures",
+			zap.String("path", route.Path),
+			zap.String("backend", backend),
+			zap.Int("consecutiveFailures", h.cfg.Proxy.OutlierConsecutiveFailures),
+			zap.Duration("ejectionTime", h.cfg.Proxy.OutlierEjectionTime))
 	}
+}
 
-	routeMap := make(map[string]*config.Route)
-	for _, route := range routes {
-		routeMap[route.Path] = route
+// isHTTPS reports the effective scheme, honoring X-Forwarded-Proto set by a
+// TLS-terminating load balancer.
+func isHTTPS(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
 	}
+	return strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
 
-	h.routes = routeMap
+// ReloadRoutes replaces the routes served by the proxy, and seen by the
+// middlewares sharing the store, with the ones in the database. The admin
+// endpoints call it after every change; other writers of the database are
+// picked up by the periodic route reconciliation.
+func (h *Handler) ReloadRoutes() error {
+	if _, _, err := h.routes.Reload(); err != nil {
+		h.logger.Error("Failed to load routes", zap.Error(err))
+		return err
+	}
 	return nil
 }
 
 func (h *Handler) GetMetrics(c *gin.Context) {
 	path := c.Query("path")
 
+	// Os contadores são gravados no banco pelo middleware de analytics, então são lidos de lá
+	routes, err := h.db.GetRoutes()
+	if err != nil {
+		h.logger.Error("Failed to load routes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load metrics"})
+		return
+	}
+
 	// Se o path não for especificado, retorne métricas para todas as rotas
 	if path == "" {
 		var allMetrics []RouteMetrics
-		for _, route := range h.routes {
+		for _, route := range routes {
 			allMetrics = append(allMetrics, RouteMetrics{
 				CallCount:     int(route.CallCount),
 				TotalResponse: route.TotalResponse,
 				ServiceURL:    route.ServiceURL,
 				Path:          route.Path,
-				// Mapeie outros campos conforme necessário
+
+				SchemaValidationFailed: h.schemas.failureCount(route.Path),
+				DeprecatedCalls:        h.deprecatedCalls.get(route.Path),
+				ErrorPagesServed:       h.errorPagesServed.get(route.Path),
+				MirrorsSucceeded:       h.mirrorsSucceeded.get(route.Path),
+				MirrorsFailed:          h.mirrorsFailed.get(route.Path),
+				RequestTimeout:         h.requestTimeout(route),
 			})
 		}
 		c.JSON(http.StatusOK, allMetrics)
@@ -107,8 +346,14 @@ func (h *Handler) GetMetrics(c *gin.Context) {
 	}
 
 	// Se um path específico for especificado, retorne métricas apenas para essa rota
-	route, exists := h.routes[path]
-	if !exists {
+	var route *config.Route
+	for _, candidate := range routes {
+		if candidate.Path == path {
+			route = candidate
+			break
+		}
+	}
+	if route == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
 		return
 	}
@@ -118,12 +363,23 @@ func (h *Handler) GetMetrics(c *gin.Context) {
 		TotalResponse: route.TotalResponse,
 		ServiceURL:    route.ServiceURL,
 		Path:          route.Path,
-		// Mapeie outros campos conforme necessário
+
+		SchemaValidationFailed: h.schemas.failureCount(route.Path),
+		DeprecatedCalls:        h.deprecatedCalls.get(route.Path),
+		ErrorPagesServed:       h.errorPagesServed.get(route.Path),
+		MirrorsSucceeded:       h.mirrorsSucceeded.get(route.Path),
+		MirrorsFailed:          h.mirrorsFailed.get(route.Path),
+		RequestTimeout:         h.requestTimeout(route),
 	}
 
 	c.JSON(http.StatusOK, specificMetrics)
 }
 
+// GetConfig returns the effective configuration with secrets redacted.
+func (h *Handler) GetConfig(c *gin.Context) {
+	c.JSON(http.StatusOK, h.cfg.Redacted())
+}
+
 func (h *Handler) RegisterAPI(c *gin.Context) {
 	var newRoutes []config.Route
 	err := c.BindJSON(&newRoutes)
@@ -132,6 +388,23 @@ func (h *Handler) RegisterAPI(c *gin.Context) {
 		return
 	}
 
+	if conflicts := config.FindRouteConflicts(h.routes.All(), newRoutes); len(conflicts) > 0 {
+		for _, conflict := range conflicts {
+			h.logger.Warn("Route conflict detected", zap.String("conflict", conflict.String()))
+		}
+		if h.cfg.Routes.ConflictPolicy == config.ConflictPolicyReject {
+			c.JSON(http.StatusConflict, gin.H{"error": "Conflicting routes", "conflicts": conflicts})
+			return
+		}
+	}
+
+	for _, newRoute := range newRoutes {
+		if err := newRoute.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "path": newRoute.Path})
+			return
+		}
+	}
+
 	for _, newRoute := range newRoutes {
 		err = h.db.AddRoute(&newRoute)
 		if err != nil {
@@ -141,12 +414,18 @@ func (h *Handler) RegisterAPI(c *gin.Context) {
 		}
 	}
 
-	if err := h.updateRoutes(); err != nil {
+	if err := h.ReloadRoutes(); err != nil {
 		h.logger.Error("Failed to update routes", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routes"})
 		return
 	}
 
+	registered := make([]*config.Route, len(newRoutes))
+	for i := range newRoutes {
+		registered[i] = &newRoutes[i]
+	}
+	h.preconnect(registered)
+
 	// Log info about the registered routes
 	h.logger.Info("Routes registered successfully",
 		zap.Int("totalRoutes", len(newRoutes)),
@@ -167,7 +446,7 @@ func getRoutePaths(routes []config.Route) []string {
 func RouteExists(engine *gin.Engine, methods []string, path string) bool {
 	for _, route := range engine.Routes() {
 		for _, method := range methods {
-			if route.Method == method && route.Path == path {
+			if strings.EqualFold(route.Method, method) && route.Path == path {
 				return true
 			}
 		}
@@ -183,6 +462,18 @@ func (h *Handler) ListAPIs(c *gin.Context) {
 		return
 	}
 
+	// Filtros opcionais por tag e grupo
+	tag, group := c.Query("tag"), c.Query("group")
+	if tag != "" || group != "" {
+		filtered := []*config.Route{}
+		for _, route := range routes {
+			if (tag == "" || route.HasTag(tag)) && (group == "" || route.Group == group) {
+				filtered = append(filtered, route)
+			}
+		}
+		routes = filtered
+	}
+
 	c.JSON(http.StatusOK, routes)
 }
 
@@ -194,14 +485,31 @@ func (h *Handler) UpdateAPI(c *gin.Context) {
 		return
 	}
 
+	if err := updatedRoute.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updatedRoute.Version == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "version is required, use the value returned by /admin/apis"})
+		return
+	}
+
 	err = h.db.UpdateRoute(&updatedRoute)
+	if errors.Is(err, database.ErrVersionConflict) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Route was modified by another request, reload it and retry"})
+		return
+	}
+	if errors.Is(err, database.ErrRouteNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Route not found"})
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to update route in database", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the API"})
 		return
 	}
 
-	if err := h.updateRoutes(); err != nil {
+	if err := h.ReloadRoutes(); err != nil {
 		h.logger.Error("Failed to update routes", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routes"})
 		return
@@ -210,6 +518,56 @@ func (h *Handler) UpdateAPI(c *gin.Context) {
 	c.JSON(http.StatusOK, updatedRoute)
 }
 
+type bulkRequest struct {
+	Action database.BulkAction `json:"action"`
+	Tag    string              `json:"tag"`
+	Group  string              `json:"group"`
+}
+
+// BulkRoutes enables, disables or deletes every route matching a tag and/or
+// group in one transaction.
+func (h *Handler) BulkRoutes(c *gin.Context) {
+	var req bulkRequest
+	if err := c.BindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch req.Action {
+	case database.BulkEnable, database.BulkDisable, database.BulkDelete:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "action must be enable, disable or delete"})
+		return
+	}
+	if req.Tag == "" && req.Group == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag or group is required"})
+		return
+	}
+
+	paths, err := h.db.BulkApply(req.Action, func(route *config.Route) bool {
+		return (req.Tag == "" || route.HasTag(req.Tag)) && (req.Group == "" || route.Group == req.Group)
+	})
+	if err != nil {
+		h.logger.Error("Failed to apply bulk operation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply bulk operation"})
+		return
+	}
+
+	if err := h.ReloadRoutes(); err != nil {
+		h.logger.Error("Failed to update routes", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routes"})
+		return
+	}
+
+	h.logger.Info("Bulk route operation applied",
+		zap.String("action", string(req.Action)),
+		zap.String("tag", req.Tag),
+		zap.String("group", req.Group),
+		zap.Strings("routes", paths))
+
+	c.JSON(http.StatusOK, gin.H{"action": req.Action, "affected": paths})
+}
+
 func (h *Handler) DeleteAPI(c *gin.Context) {
 	path := c.Query("path")
 	if path == "" {
@@ -224,7 +582,7 @@ func (h *Handler) DeleteAPI(c *gin.Context) {
 		return
 	}
 
-	if err := h.updateRoutes(); err != nil {
+	if err := h.ReloadRoutes(); err != nil {
 		h.logger.Error("Failed to update routes", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routes"})
 		return
@@ -0,0 +1,365 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/internal/routestore"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProxy is a Handler over a throwaway database, registered on a gin
+// engine the way cmd/main.go does and served on a real listener (the reverse
+// proxy needs a ResponseWriter that supports CloseNotify).
+type testProxy struct {
+	*Handler
+	server *httptest.Server
+}
+
+func newTestProxy(t *testing.T, cfg *config.Config, routes ...*config.Route) *testProxy {
+	t.Helper()
+	return newTestProxyWithLogger(t, zap.NewNop(), cfg, routes...)
+}
+
+func newTestProxyWithLogger(t *testing.T, logger *zap.Logger, cfg *config.Config, routes ...*config.Route) *testProxy {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "routes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range routes {
+		if err := db.AddRoute(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := routestore.New(db, cfg.Routes.TrailingSlash)
+	if _, _, err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(db, logger, cfg, store)
+
+	r := gin.New()
+	r.RedirectTrailingSlash = cfg.Routes.TrailingSlash == config.TrailingSlashRedirect
+	for _, route := range routes {
+		for _, path := range route.MatchPaths(cfg.Routes.TrailingSlash) {
+			for _, method := range route.Methods {
+				r.Handle(method, path, func(c *gin.Context) {
+					h.ServeHTTP(c.Writer, c.Request)
+				})
+			}
+		}
+	}
+	r.POST("/admin/register", h.RegisterAPI)
+	r.PUT("/admin/update", h.UpdateAPI)
+	r.GET("/admin/metrics", h.GetMetrics)
+	r.GET("/admin/backends", h.GetBackendLoad)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return &testProxy{Handler: h, server: server}
+}
+
+// testClient doesn't follow redirects, so tests see the gateway's own answer.
+var testClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// do sends a request to the gateway and returns the response with its body
+// read.
+func (p *testProxy) do(t *testing.T, req *http.Request) (*http.Response, string) {
+	t.Helper()
+	target, err := url.Parse(p.server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+	resp, err := testClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func (p *testProxy) get(t *testing.T, path string) (*http.Response, string) {
+	t.Helper()
+	return p.do(t, newRequest(t, http.MethodGet, path, nil))
+}
+
+// newRequest builds a client request for path; testProxy.do sends it to the
+// gateway.
+func newRequest(t *testing.T, method, path string, body []byte) *http.Request {
+	t.Helper()
+	req, err := http.NewRequest(method, path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func newTestRoute(path, serviceURL string, methods ...string) *config.Route {
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet}
+	}
+	return &config.Route{Path: path, ServiceURL: serviceURL, Methods: methods, IsActive: true}
+}
+
+func TestUpdateAPI(t *testing.T) {
+	route := newTestRoute("/users", "http://users.internal")
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	// Os casos rodam em sequência: a primeira atualização avança a versão para 2
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"current version", `{"path":"/users","serviceURL":"http://users-v2.internal","methods":["GET"],"isActive":true,"version":1}`, http.StatusOK, ""},
+		{"stale version", `{"path":"/users","serviceURL":"http://users-v3.internal","methods":["GET"],"isActive":true,"version":1}`, http.StatusConflict, "modified by another request"},
+		{"missing route", `{"path":"/missing","serviceURL":"http://users-v3.internal","methods":["GET"],"isActive":true,"version":2}`, http.StatusNotFound, "Route not found"},
+		{"missing version", `{"path":"/users","serviceURL":"http://users-v3.internal","methods":["GET"],"isActive":true}`, http.StatusBadRequest, "version is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := p.do(t, newRequest(t, http.MethodPut, "/admin/update", []byte(tt.body)))
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d (body %s)", resp.StatusCode, tt.status, body)
+			}
+			if !strings.Contains(body, tt.message) {
+				t.Fatalf("body %s, want it to mention %q", body, tt.message)
+			}
+		})
+	}
+
+	// Só a primeira atualização chegou ao banco
+	routes, err := p.db.GetRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored := routes[0]; len(routes) != 1 || stored.ServiceURL != "http://users-v2.internal" || stored.Version != 2 {
+		t.Fatalf("stored route %s version %d, want the first update only", stored.ServiceURL, stored.Version)
+	}
+}
+
+func TestTrailingSlashPolicies(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	tests := []struct {
+		policy       string
+		slashStatus  int
+		slashHeaders map[string]string
+	}{
+		{config.TrailingSlashStrict, http.StatusNotFound, nil},
+		{config.TrailingSlashRedirect, http.StatusMovedPermanently, map[string]string{"Location": "/api/users"}},
+		{config.TrailingSlashIgnore, http.StatusOK, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			cfg.Routes.TrailingSlash = tt.policy
+			p := newTestProxy(t, cfg, newTestRoute("/api/users", backend.URL))
+
+			if resp, _ := p.get(t, "/api/users"); resp.StatusCode != http.StatusOK {
+				t.Fatalf("/api/users: status %d, want 200", resp.StatusCode)
+			}
+			resp, _ := p.get(t, "/api/users/")
+			if resp.StatusCode != tt.slashStatus {
+				t.Fatalf("/api/users/: status %d, want %d", resp.StatusCode, tt.slashStatus)
+			}
+			for name, want := range tt.slashHeaders {
+				if got := resp.Header.Get(name); got != want {
+					t.Fatalf("/api/users/: %s %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTrailingSlashDefault(t *testing.T) {
+	if cfg := newTestConfig(t); cfg.Routes.TrailingSlash != config.TrailingSlashRedirect {
+		t.Fatalf("default policy %q, want %q as gin.Default behaved before the option", cfg.Routes.TrailingSlash, config.TrailingSlashRedirect)
+	}
+}
+
+func TestRequireHTTPS(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	route := newTestRoute("/payments", backend.URL)
+	route.RequireHTTPS = true
+	// A rota vem do banco, então o flag também é conferido depois de persistido
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	tests := []struct {
+		forwardedProto string
+		want           int
+	}{
+		{"", http.StatusForbidden},
+		{"http", http.StatusForbidden},
+		{"https", http.StatusOK},
+		{"HTTPS", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := newRequest(t, http.MethodGet, "/payments", nil)
+		if tt.forwardedProto != "" {
+			req.Header.Set("X-Forwarded-Proto", tt.forwardedProto)
+		}
+		if resp, _ := p.do(t, req); resp.StatusCode != tt.want {
+			t.Fatalf("X-Forwarded-Proto %q: status %d, want %d", tt.forwardedProto, resp.StatusCode, tt.want)
+		}
+	}
+}
+
+func TestRegisterAPIRejectsEmptyMethods(t *testing.T) {
+	p := newTestProxy(t, newTestConfig(t))
+
+	for _, methods := range []string{`[]`, `null`} {
+		body := fmt.Sprintf(`{"path":"/users","serviceURL":"http://users.internal","methods":%s,"isActive":true}`, methods)
+		resp, _ := p.do(t, newRequest(t, http.MethodPost, "/admin/register", []byte(body)))
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("methods %s: status %d, want 400", methods, resp.StatusCode)
+		}
+	}
+	if routes := p.routes.All(); len(routes) != 0 {
+		t.Fatalf("%d routes registered, want none", len(routes))
+	}
+}
+
+func TestPreserveHostHeader(t *testing.T) {
+	var gotHost string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+	}))
+	defer backend.Close()
+	backendHost := backend.Listener.Addr().String()
+
+	for _, preserve := range []bool{false, true} {
+		route := newTestRoute("/site", backend.URL)
+		route.PreserveHostHeader = preserve
+		p := newTestProxy(t, newTestConfig(t), route)
+
+		req := newRequest(t, http.MethodGet, "/site", nil)
+		req.Host = "shop.example.com"
+		if resp, _ := p.do(t, req); resp.StatusCode != http.StatusOK {
+			t.Fatalf("preserve=%v: status %d", preserve, resp.StatusCode)
+		}
+
+		want := backendHost
+		if preserve {
+			want = "shop.example.com"
+		}
+		if gotHost != want {
+			t.Fatalf("preserve=%v: backend saw Host %q, want %q", preserve, gotHost, want)
+		}
+	}
+}
+
+func TestStreamingRouteFlushesPromptly(t *testing.T) {
+	const (
+		chunkSize = 64 << 10
+		chunks    = 1024 // 64MB
+	)
+	release := make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len("first")+chunkSize*chunks))
+		w.Write([]byte("first"))
+		w.(http.Flusher).Flush()
+		<-release
+
+		chunk := bytes.Repeat([]byte("x"), chunkSize)
+		for i := 0; i < chunks; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	defer backend.Close()
+	defer close(release)
+
+	route := newTestRoute("/download", backend.URL)
+	route.Streaming = true
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	// Cancelar a requisição numa falha destrava backend e proxy antes dos Close
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.server.URL+"/download", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	first := make([]byte, len("first"))
+	read := make(chan result, 1)
+	// Com Content-Length o proxy só envia antes de terminar se a rota fizer flush a cada escrita
+	go func() {
+		resp, err := testClient.Do(req)
+		if err == nil {
+			_, err = io.ReadFull(resp.Body, first)
+		}
+		read <- result{resp, err}
+	}()
+	var resp *http.Response
+	select {
+	case r := <-read:
+		if r.err != nil || string(first) != "first" {
+			t.Fatalf("first chunk %q, %v", first, r.err)
+		}
+		resp = r.resp
+	case <-time.After(5 * time.Second):
+		t.Fatal("first chunk not flushed while the backend was still writing")
+	}
+	defer resp.Body.Close()
+
+	release <- struct{}{}
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil || n != chunkSize*chunks {
+		t.Fatalf("streamed %d bytes, %v; want %d", n, err, chunkSize*chunks)
+	}
+
+	// Backend, proxy e cliente rodam no mesmo processo: tudo que foi alocado é bem menor que o corpo
+	var after runtime.MemStats
+	runtime.ReadMemStats(&after)
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > chunkSize*chunks/4 {
+		t.Fatalf("allocated %d bytes streaming a %d byte body", allocated, chunkSize*chunks)
+	}
+}
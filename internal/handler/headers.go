@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"net/http"
+)
+
+// alwaysAllowedHeaders survive an allowlist so the response stays decodable.
+var alwaysAllowedHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding"}
+
+// filterResponseHeaders strips backend response headers that shouldn't reach
+// clients. The route's allowlist takes precedence over the global one, and
+// denylists from both are applied.
+func (h *Handler) filterResponseHeaders(header http.Header, route *config.Route) {
+	allow := route.ResponseHeaderAllowlist
+	if len(allow) == 0 {
+		allow = h.cfg.Proxy.ResponseHeaderAllowlist
+	}
+
+	if len(allow) > 0 {
+		keep := make(map[string]bool, len(allow)+len(alwaysAllowedHeaders))
+		for _, name := range allow {
+			keep[http.CanonicalHeaderKey(name)] = true
+		}
+		for _, name := range alwaysAllowedHeaders {
+			keep[name] = true
+		}
+		for name := range header {
+			if !keep[http.CanonicalHeaderKey(name)] {
+				header.Del(name)
+			}
+		}
+	}
+
+	for _, name := range h.cfg.Proxy.ResponseHeaderDenylist {
+		header.Del(name)
+	}
+	for _, name := range route.ResponseHeaderDenylist {
+		header.Del(name)
+	}
+}
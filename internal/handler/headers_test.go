@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newLeakyBackend answers with internal headers the gateway should filter.
+func newLeakyBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		h.Set("Server", "nginx/1.18.0")
+		h.Set("X-Powered-By", "Express")
+		h.Set("X-Debug-Trace", "at handler.go:42")
+		h.Set("X-Request-Cost", "3")
+		h.Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func expectHeaders(t *testing.T, resp *http.Response, present, absent []string) {
+	t.Helper()
+	for _, name := range present {
+		if resp.Header.Get(name) == "" {
+			t.Fatalf("header %s missing", name)
+		}
+	}
+	for _, name := range absent {
+		if got := resp.Header.Get(name); got != "" {
+			t.Fatalf("header %s = %q reached the client", name, got)
+		}
+	}
+}
+
+func TestResponseHeaderDefaultDenylist(t *testing.T) {
+	backend := newLeakyBackend(t)
+	p := newTestProxy(t, newTestConfig(t), newTestRoute("/api", backend.URL))
+
+	resp, _ := p.get(t, "/api")
+	expectHeaders(t, resp,
+		[]string{"Content-Type", "X-Debug-Trace", "X-Request-Cost"},
+		[]string{"Server", "X-Powered-By"})
+}
+
+func TestResponseHeaderRouteDenylist(t *testing.T) {
+	backend := newLeakyBackend(t)
+	route := newTestRoute("/api", backend.URL)
+	route.ResponseHeaderDenylist = []string{"x-debug-trace"}
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	// A lista da rota soma-se à global
+	resp, _ := p.get(t, "/api")
+	expectHeaders(t, resp,
+		[]string{"Content-Type", "X-Request-Cost"},
+		[]string{"Server", "X-Powered-By", "X-Debug-Trace"})
+}
+
+func TestResponseHeaderAllowlist(t *testing.T) {
+	backend := newLeakyBackend(t)
+	cfg := newTestConfig(t)
+	cfg.Proxy.ResponseHeaderAllowlist = []string{"X-Debug-Trace"}
+
+	route := newTestRoute("/api", backend.URL)
+	route.ResponseHeaderAllowlist = []string{"X-Request-Cost", "Server"}
+	route.ResponseHeaderDenylist = []string{"X-Request-Cost"}
+	global := newTestRoute("/global", backend.URL)
+	p := newTestProxy(t, cfg, route, global)
+
+	// A allowlist da rota substitui a global; Content-Type sempre passa e a denylist ainda vale
+	resp, _ := p.get(t, "/api")
+	expectHeaders(t, resp,
+		[]string{"Content-Type", "Content-Length"},
+		[]string{"X-Request-Cost", "Server", "X-Powered-By", "X-Debug-Trace"})
+
+	resp, _ = p.get(t, "/global")
+	expectHeaders(t, resp,
+		[]string{"Content-Type", "X-Debug-Trace"},
+		[]string{"X-Request-Cost", "Server", "X-Powered-By"})
+}
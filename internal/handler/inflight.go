@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type BackendLoad struct {
+	Backend  string `json:"backend"`
+	InFlight int64  `json:"inFlight"`
+}
+
+// inFlightTracker counts the upstream calls currently open per backend. The
+// backend label is reduced to scheme and host so paths and query strings
+// can't multiply the number of entries.
+type inFlightTracker struct {
+	mu       sync.RWMutex
+	counters map[string]*atomic.Int64
+}
+
+func newInFlightTracker() *inFlightTracker {
+	return &inFlightTracker{counters: make(map[string]*atomic.Int64)}
+}
+
+func normalizeBackend(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "invalid"
+	}
+	return strings.ToLower(u.Scheme) + "://" + strings.ToLower(u.Host)
+}
+
+func (t *inFlightTracker) counter(backend string) *atomic.Int64 {
+	label := normalizeBackend(backend)
+
+	t.mu.RLock()
+	c, ok := t.counters[label]
+	t.mu.RUnlock()
+	if ok {
+		return c
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c, ok = t.counters[label]; !ok {
+		c = &atomic.Int64{}
+		t.counters[label] = c
+	}
+	return c
+}
+
+// begin marks a call to backend as started and returns the function that
+// marks it as finished.
+func (t *inFlightTracker) begin(backend string) func() {
+	c := t.counter(backend)
+	c.Add(1)
+	var once sync.Once
+	return func() { once.Do(func() { c.Add(-1) }) }
+}
+
+func (t *inFlightTracker) get(backend string) int64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if c, ok := t.counters[normalizeBackend(backend)]; ok {
+		return c.Load()
+	}
+	return 0
+}
+
+func (t *inFlightTracker) snapshot() []BackendLoad {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	loads := make([]BackendLoad, 0, len(t.counters))
+	for backend, c := range t.counters {
+		loads = append(loads, BackendLoad{Backend: backend, InFlight: c.Load()})
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].Backend < loads[j].Backend })
+	return loads
+}
+
+// countingTransport keeps a backend's in-flight count raised from the moment
+// the request is sent until the response body is closed.
+type countingTransport struct {
+	next     http.RoundTripper
+	backend  string
+	inFlight *inFlightTracker
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	done := t.inFlight.begin(t.backend)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	// Upgrades (websocket) precisam do body original, que implementa io.ReadWriteCloser
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		done()
+		return resp, nil
+	}
+	resp.Body = &doneOnClose{ReadCloser: resp.Body, done: done}
+	return resp, nil
+}
+
+type doneOnClose struct {
+	io.ReadCloser
+	done func()
+}
+
+func (b *doneOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.done()
+	return err
+}
+
+// GetBackendLoad lists the number of in-flight upstream requests per backend
+// and the outlier-detection state of the balanced ones.
+func (h *Handler) GetBackendLoad(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"backends": h.inFlight.snapshot(), "outliers": h.outliers.snapshot()})
+}
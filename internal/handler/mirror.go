@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	maxConcurrentMirrors = 50
+	mirrorTimeout        = 10 * time.Second
+)
+
+// mirrorSlots limita quantas requisições espelhadas podem estar em andamento.
+var mirrorSlots = make(chan struct{}, maxConcurrentMirrors)
+
+func shouldMirror(route *config.Route) bool {
+	if route.MirrorURL == "" {
+		return false
+	}
+	if route.MirrorPercent == 0 || route.MirrorPercent >= 100 {
+		return true
+	}
+	return rand.Intn(100) < route.MirrorPercent
+}
+
+// mirrorClient sends mirrored requests through the route's transport, so they
+// use the same CA bundle, upstream proxy and DNS cache as the primary call.
+// Redirects are returned as is instead of being followed.
+func (h *Handler) mirrorClient(route *config.Route) *http.Client {
+	return &http.Client{
+		Transport: h.transportFor(route),
+		Timeout:   mirrorTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// mirror sends a copy of r to the route's mirror target in the background.
+// The body is buffered and restored on r so the primary request is untouched;
+// requests whose body exceeds the replay limit are not mirrored. Each mirrored
+// request is counted as succeeded or failed (error, 5xx or dropped).
+func (h *Handler) mirror(r *http.Request, route *config.Route) {
+	target, err := url.Parse(route.MirrorURL)
+	if err != nil {
+		h.logger.Warn("Invalid mirror URL", zap.String("path", route.Path), zap.Error(err))
+		return
+	}
+
+	body, buffered, err := bufferBody(r, h.cfg.Proxy.MaxReplayBodyBytes)
+	if err != nil {
+		h.logger.Warn("Failed to read body for mirroring", zap.String("path", route.Path), zap.Error(err))
+		return
+	}
+	if !buffered {
+		h.logger.Debug("Body too large to mirror, skipping", zap.String("path", route.Path))
+		return
+	}
+
+	// Se não houver vaga, o espelhamento é descartado para não acumular goroutines
+	select {
+	case mirrorSlots <- struct{}{}:
+	default:
+		h.mirrorsFailed.inc(route.Path)
+		h.logger.Warn("Mirror request dropped, too many in flight", zap.String("path", route.Path))
+		return
+	}
+
+	mirrorURL := *r.URL
+	mirrorURL.Scheme = target.Scheme
+	mirrorURL.Host = target.Host
+	mirrorURL.Path = singleJoiningSlash(target.Path, r.URL.Path)
+	header := r.Header.Clone()
+	method := r.Method
+
+	go func() {
+		defer func() { <-mirrorSlots }()
+
+		req, err := http.NewRequest(method, mirrorURL.String(), bytes.NewReader(body))
+		if err != nil {
+			h.mirrorsFailed.inc(route.Path)
+			h.logger.Warn("Failed to build mirror request", zap.String("path", route.Path), zap.Error(err))
+			return
+		}
+		req.Header = header
+
+		done := h.inFlight.begin(route.MirrorURL)
+		defer done()
+
+		resp, err := h.mirrorClient(route).Do(req)
+		if err != nil {
+			h.mirrorsFailed.inc(route.Path)
+			h.backends.recordError(route.MirrorURL, err)
+			h.logger.Warn("Mirror request failed", zap.String("path", route.Path), zap.String("mirrorURL", route.MirrorURL), zap.Error(err))
+			return
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		h.backends.recordStatus(route.MirrorURL, resp.StatusCode)
+		if resp.StatusCode >= http.StatusInternalServerError {
+			h.mirrorsFailed.inc(route.Path)
+		} else {
+			h.mirrorsSucceeded.inc(route.Path)
+		}
+
+		h.logger.Debug("Mirror request completed",
+			zap.String("path", route.Path),
+			zap.String("mirrorURL", route.MirrorURL),
+			zap.Int("status", resp.StatusCode))
+	}()
+}
+
+func singleJoiningSlash(a, b string) string {
+	aslash := strings.HasSuffix(a, "/")
+	bslash := strings.HasPrefix(b, "/")
+	switch {
+	case aslash && bslash:
+		return a + b[1:]
+	case !aslash && !bslash:
+		return a + "/" + b
+	}
+	return a + b
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMirror(t *testing.T) {
+	var redirected atomic.Int32
+	mirror := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/elsewhere":
+			redirected.Add(1)
+		case strings.HasPrefix(r.URL.Path, "/failing"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.Redirect(w, r, "/elsewhere", http.StatusFound)
+		}
+	}))
+	defer mirror.Close()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	// O espelho só é confiável pela CA global, que o http.DefaultClient não conhece
+	cfg := newTestConfig(t)
+	cfg.Proxy.CACertPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: mirror.Certificate().Raw}))
+	cfg.Proxy.CAAppendSystem = false
+
+	orders := newTestRoute("/orders", backend.URL)
+	orders.MirrorURL = mirror.URL
+	failing := newTestRoute("/failing", backend.URL)
+	failing.MirrorURL = mirror.URL
+	p := newTestProxy(t, cfg, orders, failing)
+
+	for i := 0; i < 3; i++ {
+		p.get(t, "/orders")
+	}
+	for i := 0; i < 2; i++ {
+		p.get(t, "/failing")
+	}
+
+	waitForMirrors(t, p, "/orders", 3, 0)
+	waitForMirrors(t, p, "/failing", 0, 2)
+	if n := redirected.Load(); n != 0 {
+		t.Fatalf("mirror followed %d redirects, want none", n)
+	}
+}
+
+// waitForMirrors polls the admin metrics until the mirrored requests of path,
+// sent in the background, have all been counted.
+func waitForMirrors(t *testing.T, p *testProxy, path string, succeeded, failed int64) {
+	t.Helper()
+	var metrics RouteMetrics
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		_, body := p.get(t, "/admin/metrics?path="+path)
+		if err := json.Unmarshal([]byte(body), &metrics); err != nil {
+			t.Fatal(err)
+		}
+		if metrics.MirrorsSucceeded == succeeded && metrics.MirrorsFailed == failed {
+			return
+		}
+	}
+	t.Fatalf("%s: mirrorsSucceeded %d, mirrorsFailed %d, want %d and %d",
+		path, metrics.MirrorsSucceeded, metrics.MirrorsFailed, succeeded, failed)
+}
@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// OutlierStatus is the outlier-detection state of one balanced backend.
+type OutlierStatus struct {
+	Backend             string     `json:"backend"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	Ejected             bool       `json:"ejected"`
+	EjectedUntil        *time.Time `json:"ejectedUntil,omitempty"`
+	Ejections           int64      `json:"ejections"`
+}
+
+// outlierDetector takes backends of balanced routes out of rotation after
+// consecutive failures (5xx responses or connection errors). Once the ejection
+// time is over the backend gets traffic again: one success re-admits it, one
+// more failure ejects it again. A threshold of zero disables detection.
+type outlierDetector struct {
+	consecutive int
+	ejection    time.Duration
+
+	mu       sync.Mutex
+	backends map[string]*outlierState
+}
+
+type outlierState struct {
+	failures     int
+	ejectedUntil time.Time
+	ejections    int64
+}
+
+func newOutlierDetector(consecutive int, ejection time.Duration) *outlierDetector {
+	return &outlierDetector{consecutive: consecutive, ejection: ejection, backends: make(map[string]*outlierState)}
+}
+
+func (o *outlierDetector) recordSuccess(backend string) {
+	if o.consecutive <= 0 {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if state, ok := o.backends[backend]; ok {
+		state.failures = 0
+	}
+}
+
+// recordFailure returns true when the failure ejects the backend.
+func (o *outlierDetector) recordFailure(backend string) bool {
+	if o.consecutive <= 0 {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	state, ok := o.backends[backend]
+	if !ok {
+		state = &outlierState{}
+		o.backends[backend] = state
+	}
+	state.failures++
+	now := time.Now()
+	if state.failures < o.consecutive || now.Before(state.ejectedUntil) {
+		return false
+	}
+	state.ejectedUntil = now.Add(o.ejection)
+	state.ejections++
+	return true
+}
+
+func (o *outlierDetector) isEjected(backend string) bool {
+	if o.consecutive <= 0 {
+		return false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	state, ok := o.backends[backend]
+	return ok && time.Now().Before(state.ejectedUntil)
+}
+
+func (o *outlierDetector) snapshot() []OutlierStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]OutlierStatus, 0, len(o.backends))
+	for backend, state := range o.backends {
+		status := OutlierStatus{Backend: backend, ConsecutiveFailures: state.failures, Ejections: state.ejections}
+		if now.Before(state.ejectedUntil) {
+			until := state.ejectedUntil
+			status.Ejected, status.EjectedUntil = true, &until
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Backend < statuses[j].Backend })
+	return statuses
+}
+
+func (o *outlierDetector) reset() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	cleared := len(o.backends)
+	o.backends = make(map[string]*outlierState)
+	return cleared
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newFlakyBackend answers with its name, or with 500 while failing is set.
+func newFlakyBackend(t *testing.T, name string, failing *atomic.Bool) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing != nil && failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(name))
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+// hits sends n requests and counts the answers by body ("" for errors).
+func (p *testProxy) hits(t *testing.T, path string, n int) map[string]int {
+	t.Helper()
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		_, body := p.get(t, path)
+		counts[body]++
+	}
+	return counts
+}
+
+func (p *testProxy) outlierStatus(t *testing.T, backend string) OutlierStatus {
+	t.Helper()
+	var load struct{ Outliers []OutlierStatus }
+	_, body := p.get(t, "/admin/backends")
+	if err := json.Unmarshal([]byte(body), &load); err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range load.Outliers {
+		if status.Backend == backend {
+			return status
+		}
+	}
+	return OutlierStatus{Backend: backend}
+}
+
+func TestOutlierEjection(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	healthy := newFlakyBackend(t, "healthy", nil)
+	flaky := newFlakyBackend(t, "flaky", &failing)
+
+	cfg := newTestConfig(t)
+	cfg.Proxy.OutlierConsecutiveFailures = 2
+	cfg.Proxy.OutlierEjectionTime = 200 * time.Millisecond
+	route := newTestRoute("/api", healthy.URL)
+	route.Upstreams = []config.Upstream{{URL: healthy.URL}, {URL: flaky.URL}}
+	p := newTestProxy(t, cfg, route)
+
+	// Round-robin alternado: a instância com falha recebe 2 das 4 primeiras e é ejetada
+	if counts := p.hits(t, "/api", 4); counts["healthy"] != 2 || counts[""] != 2 {
+		t.Fatalf("before ejection: %v, want 2 healthy and 2 failures", counts)
+	}
+	status := p.outlierStatus(t, flaky.URL)
+	if !status.Ejected || status.Ejections != 1 {
+		t.Fatalf("flaky backend: %+v, want ejected once", status)
+	}
+	if counts := p.hits(t, "/api", 6); counts["healthy"] != 6 {
+		t.Fatalf("while ejected: %v, want every request on the healthy backend", counts)
+	}
+
+	// Passado o tempo de ejeção, uma nova falha a ejeta de novo na hora
+	time.Sleep(250 * time.Millisecond)
+	p.hits(t, "/api", 2)
+	if status := p.outlierStatus(t, flaky.URL); !status.Ejected || status.Ejections != 2 {
+		t.Fatalf("after a failed probe: %+v, want ejected again", status)
+	}
+
+	// Recuperada, um sucesso a readmite no balanceamento
+	failing.Store(false)
+	time.Sleep(250 * time.Millisecond)
+	if counts := p.hits(t, "/api", 4); counts["healthy"] != 2 || counts["flaky"] != 2 {
+		t.Fatalf("after recovery: %v, want traffic split again", counts)
+	}
+	if status := p.outlierStatus(t, flaky.URL); status.Ejected || status.ConsecutiveFailures != 0 {
+		t.Fatalf("after recovery: %+v, want re-admitted", status)
+	}
+}
+
+func TestOutlierAllEjected(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	a := newFlakyBackend(t, "a", &failing)
+	b := newFlakyBackend(t, "b", &failing)
+
+	cfg := newTestConfig(t)
+	cfg.Proxy.OutlierConsecutiveFailures = 1
+	cfg.Proxy.OutlierEjectionTime = time.Minute
+	route := newTestRoute("/api", a.URL)
+	route.Upstreams = []config.Upstream{{URL: a.URL}, {URL: b.URL}}
+	p := newTestProxy(t, cfg, route)
+
+	p.hits(t, "/api", 2)
+	failing.Store(false)
+
+	// Com todas ejetadas o tráfego continua indo para todas em vez de ser recusado
+	if counts := p.hits(t, "/api", 4); counts["a"] != 2 || counts["b"] != 2 {
+		t.Fatalf("all ejected: %v, want traffic still split", counts)
+	}
+}
+
+func TestOutlierDetectionDisabled(t *testing.T) {
+	var failing atomic.Bool
+	failing.Store(true)
+	healthy := newFlakyBackend(t, "healthy", nil)
+	flaky := newFlakyBackend(t, "flaky", &failing)
+
+	cfg := newTestConfig(t)
+	cfg.Proxy.OutlierConsecutiveFailures = 0
+	route := newTestRoute("/api", healthy.URL)
+	route.Upstreams = []config.Upstream{{URL: healthy.URL}, {URL: flaky.URL}}
+	p := newTestProxy(t, cfg, route)
+
+	if counts := p.hits(t, "/api", 10); counts["healthy"] != 5 || counts[""] != 5 {
+		t.Fatalf("%v, want the failing backend kept in rotation", counts)
+	}
+}
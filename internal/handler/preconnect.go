@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"context"
+	"github.com/diillson/api-gateway-go/internal/workers"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"time"
+)
+
+const preconnectTimeout = 5 * time.Second
+
+// preconnect warms up connections to the routes' backends so the first real
+// request doesn't pay the TCP and TLS handshake. A HEAD request is issued
+// through the same transport the proxy uses, leaving the connection idle in
+// its pool, with at most Server.BulkConcurrency backends probed at once.
+// Failures are only logged. The returned channel is closed once every backend
+// has been tried.
+func (h *Handler) preconnect(routes []*config.Route) <-chan struct{} {
+	done := make(chan struct{})
+	if !h.cfg.Proxy.Preconnect {
+		close(done)
+		return done
+	}
+
+	var targets []preconnectTarget
+	seen := make(map[string]bool)
+	for _, route := range routes {
+		for _, upstream := range route.Backends() {
+			if upstream.URL == "" || seen[upstream.URL] {
+				continue
+			}
+			seen[upstream.URL] = true
+			targets = append(targets, preconnectTarget{route: route, backend: upstream.URL})
+		}
+	}
+
+	// Limita as conexões simultâneas para não esgotar sockets com muitos backends
+	go func() {
+		defer close(done)
+
+		step := workers.ProgressStep(len(targets))
+		workers.Run(h.cfg.Server.BulkConcurrency, len(targets), func(i int) {
+			h.preconnectBackend(targets[i].route, targets[i].backend)
+		}, func(finished, total int) {
+			if finished%step == 0 || finished == total {
+				h.logger.Info("Backend preconnect progress", zap.Int("done", finished), zap.Int("total", total))
+			}
+		})
+	}()
+	return done
+}
+
+// preconnectTarget is a backend to warm up with the route whose transport
+// reaches it.
+type preconnectTarget struct {
+	route   *config.Route
+	backend string
+}
+
+func (h *Handler) preconnectBackend(route *config.Route, backend string) {
+	ctx, cancel := context.WithTimeout(context.Background(), preconnectTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, backend, nil)
+	if err != nil {
+		h.logger.Warn("Failed to build preconnect request", zap.String("serviceURL", backend), zap.Error(err))
+		return
+	}
+
+	resp, err := h.transportFor(route).RoundTrip(req)
+	if err != nil {
+		h.logger.Warn("Backend preconnect failed", zap.String("serviceURL", backend), zap.Error(err))
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	h.logger.Debug("Backend preconnected", zap.String("serviceURL", backend))
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// Recording is one proxied request/response pair captured in test mode. The
+// replay tool in cmd/tools/replay reads the same format, one JSON per line.
+type Recording struct {
+	Time     time.Time     `json:"time"`
+	Route    string        `json:"route"`
+	Duration time.Duration `json:"duration"`
+
+	Method               string      `json:"method"`
+	URI                  string      `json:"uri"`
+	RequestHeader        http.Header `json:"requestHeader"`
+	RequestBody          []byte      `json:"requestBody,omitempty"`
+	RequestBodyTruncated bool        `json:"requestBodyTruncated,omitempty"`
+
+	Status                int         `json:"status"`
+	ResponseHeader        http.Header `json:"responseHeader"`
+	ResponseBody          []byte      `json:"responseBody,omitempty"`
+	ResponseBodyTruncated bool        `json:"responseBodyTruncated,omitempty"`
+}
+
+// recorder appends recordings to a file until the configured number of
+// entries is reached.
+type recorder struct {
+	cfg    config.RecordConfig
+	logger *zap.Logger
+	routes map[string]bool
+
+	mu      sync.Mutex
+	file    *os.File
+	written int
+}
+
+func newRecorder(cfg config.RecordConfig, logger *zap.Logger) *recorder {
+	if !cfg.Enabled {
+		return nil
+	}
+	routes := make(map[string]bool, len(cfg.Routes))
+	for _, path := range cfg.Routes {
+		routes[path] = true
+	}
+	logger.Warn("Traffic recording enabled, do not use in production", zap.String("file", cfg.File))
+	return &recorder{cfg: cfg, logger: logger, routes: routes}
+}
+
+// shouldRecord reports whether requests to the route are recorded. A nil
+// recorder (test mode off) records nothing.
+func (rec *recorder) shouldRecord(path string) bool {
+	if rec == nil {
+		return false
+	}
+	return len(rec.routes) == 0 || rec.routes[path]
+}
+
+// capture buffers the request body and wraps w so the exchange can be
+// recorded once the response is written. The returned function must be called
+// after the request has been served.
+func (rec *recorder) capture(w http.ResponseWriter, r *http.Request, route *config.Route) (http.ResponseWriter, func()) {
+	entry := &Recording{
+		Time:          time.Now().UTC(),
+		Route:         route.Path,
+		Method:        r.Method,
+		URI:           rec.redactURI(r.URL),
+		RequestHeader: rec.redact(r.Header),
+	}
+
+	body, buffered, err := bufferBody(r, rec.cfg.MaxBodyBytes)
+	switch {
+	case err != nil:
+		rec.logger.Warn("Failed to read body for recording", zap.String("path", route.Path), zap.Error(err))
+		entry.RequestBodyTruncated = true
+	case !buffered:
+		entry.RequestBodyTruncated = true
+	default:
+		entry.RequestBody = body
+	}
+
+	cw := &captureResponseWriter{ResponseWriter: w, limit: rec.cfg.MaxBodyBytes, status: http.StatusOK}
+	return cw, func() {
+		entry.Duration = time.Since(entry.Time)
+		entry.Status = cw.status
+		entry.ResponseHeader = rec.redact(w.Header())
+		if cw.overflow {
+			entry.ResponseBodyTruncated = true
+		} else {
+			entry.ResponseBody = cw.buf.Bytes()
+		}
+		rec.write(entry)
+	}
+}
+
+func (rec *recorder) redact(header http.Header) http.Header {
+	redacted := header.Clone()
+	for _, name := range rec.cfg.RedactHeaders {
+		if _, ok := redacted[http.CanonicalHeaderKey(name)]; ok {
+			redacted.Set(name, "[REDACTED]")
+		}
+	}
+	return redacted
+}
+
+func (rec *recorder) redactURI(u *url.URL) string {
+	query := u.Query()
+	changed := false
+	for _, name := range rec.cfg.RedactQueryParams {
+		if query.Has(name) {
+			query.Set(name, "[REDACTED]")
+			changed = true
+		}
+	}
+	if !changed {
+		return u.RequestURI()
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.RequestURI()
+}
+
+func (rec *recorder) write(entry *Recording) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		rec.logger.Warn("Failed to encode recording", zap.Error(err))
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if rec.cfg.MaxEntries > 0 && rec.written >= rec.cfg.MaxEntries {
+		return
+	}
+	if rec.file == nil {
+		rec.file, err = os.OpenFile(rec.cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			rec.logger.Error("Failed to open recording file", zap.String("file", rec.cfg.File), zap.Error(err))
+			return
+		}
+	}
+
+	if _, err := rec.file.Write(append(line, '\n')); err != nil {
+		rec.logger.Warn("Failed to write recording", zap.Error(err))
+		return
+	}
+	rec.written++
+	if rec.written == rec.cfg.MaxEntries {
+		rec.logger.Info("Recording limit reached, no more traffic will be recorded", zap.Int("entries", rec.written))
+	}
+}
+
+// captureResponseWriter keeps a bounded copy of the response for recording.
+type captureResponseWriter struct {
+	http.ResponseWriter
+	limit    int64
+	status   int
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (c *captureResponseWriter) WriteHeader(status int) {
+	c.status = status
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *captureResponseWriter) Write(b []byte) (int, error) {
+	if !c.overflow {
+		if int64(c.buf.Len()+len(b)) > c.limit {
+			c.overflow = true
+			c.buf.Reset()
+		} else {
+			c.buf.Write(b)
+		}
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *captureResponseWriter) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readRecordings waits for the requests in flight, so their recordings are
+// written, and returns the recordings in the file.
+func readRecordings(t *testing.T, p *testProxy, file string) []Recording {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := p.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var recordings []Recording
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec Recording
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid recording %q: %v", scanner.Text(), err)
+		}
+		recordings = append(recordings, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return recordings
+}
+
+func TestRecordRedactsTruncatesAndStops(t *testing.T) {
+	// O backend responde com n bytes e um cookie de sessão
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "backend-secret"})
+		w.Write([]byte(strings.Repeat("x", n)))
+	}))
+	defer backend.Close()
+
+	cfg := newTestConfig(t)
+	cfg.Record.Enabled = true
+	cfg.Record.File = filepath.Join(t.TempDir(), "recordings.jsonl")
+	cfg.Record.MaxEntries = 3
+	cfg.Record.MaxBodyBytes = 16
+	p := newTestProxy(t, cfg, newTestRoute("/items", backend.URL, http.MethodGet, http.MethodPost))
+
+	send := func(method, uri, body string) {
+		t.Helper()
+		req := newRequest(t, method, uri, []byte(body))
+		req.Header.Set("Authorization", "Bearer client-secret")
+		if resp, body := p.do(t, req); resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s %s: status %d (%s)", method, uri, resp.StatusCode, body)
+		}
+	}
+	send(http.MethodGet, "/items?n=4&token=query-secret", "")
+	send(http.MethodGet, "/items?n=64", "")
+	send(http.MethodPost, "/items?n=1", strings.Repeat("y", 64))
+	// Acima de MaxEntries nada mais é gravado
+	send(http.MethodGet, "/items?n=2", "")
+
+	recordings := readRecordings(t, p, cfg.Record.File)
+	if len(recordings) != 3 {
+		t.Fatalf("%d recordings, want MaxEntries (3)", len(recordings))
+	}
+
+	redacted := recordings[0]
+	if strings.Contains(redacted.URI, "query-secret") || !strings.Contains(redacted.URI, "token=%5BREDACTED%5D") {
+		t.Fatalf("URI %q, want the token query param redacted", redacted.URI)
+	}
+	if got := redacted.RequestHeader.Get("Authorization"); got != "[REDACTED]" {
+		t.Fatalf("request Authorization %q, want it redacted", got)
+	}
+	if got := redacted.ResponseHeader.Get("Set-Cookie"); got != "[REDACTED]" {
+		t.Fatalf("response Set-Cookie %q, want it redacted", got)
+	}
+	if string(redacted.ResponseBody) != "xxxx" || redacted.ResponseBodyTruncated {
+		t.Fatalf("response body %q (truncated %v), want it recorded whole", redacted.ResponseBody, redacted.ResponseBodyTruncated)
+	}
+
+	if large := recordings[1]; !large.ResponseBodyTruncated || len(large.ResponseBody) != 0 {
+		t.Fatalf("response over MaxBodyBytes: body %d bytes, truncated %v; want it flagged and dropped", len(large.ResponseBody), large.ResponseBodyTruncated)
+	}
+	if large := recordings[2]; !large.RequestBodyTruncated || len(large.RequestBody) != 0 {
+		t.Fatalf("request over MaxBodyBytes: body %d bytes, truncated %v; want it flagged and dropped", len(large.RequestBody), large.RequestBodyTruncated)
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/internal/auth"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ResetSummary reports what a reset cleared and reloaded.
+type ResetSummary struct {
+	RoutesLoaded          int `json:"routesLoaded"`
+	SchemasCleared        int `json:"schemasCleared"`
+	DNSEntriesCleared     int `json:"dnsEntriesCleared"`
+	LatencyWindowsCleared int `json:"latencyWindowsCleared"`
+	TransportsClosed      int `json:"transportsClosed"`
+	BalancersCleared      int `json:"balancersCleared"`
+	OutliersCleared       int `json:"outliersCleared"`
+	ResponsesCleared      int `json:"responsesCleared"`
+	RateLimitKeysCleared  int `json:"rateLimitKeysCleared"`
+}
+
+// Reset clears the proxy's caches (compiled schemas, DNS answers, latency
+// windows, per-route transports, round-robin positions, outlier ejections and cached responses) and reloads the
+// routes from the database.
+// Metrics counters are kept.
+func (h *Handler) Reset() (ResetSummary, error) {
+	var summary ResetSummary
+
+	summary.SchemasCleared = h.schemas.reset()
+	summary.DNSEntriesCleared = h.dns.reset()
+	summary.LatencyWindowsCleared = h.latencies.reset()
+	summary.TransportsClosed = h.resetTransports()
+	summary.BalancersCleared = h.balancer.reset()
+	summary.OutliersCleared = h.outliers.reset()
+	summary.ResponsesCleared = h.responses.reset()
+
+	if err := h.ReloadRoutes(); err != nil {
+		return summary, err
+	}
+	summary.RoutesLoaded = len(h.routes.All())
+	return summary, nil
+}
+
+// ResetAPI resets the gateway state: the proxy caches, then the middleware
+// state through resetMiddleware, which also clears the rate-limit counters
+// when ?rateLimits=true. It is disruptive and meant for tests and recovery.
+func (h *Handler) ResetAPI(resetMiddleware func(clearRateLimits bool) (int, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clearRateLimits := c.Query("rateLimits") == "true"
+		h.logger.Warn("Resetting gateway state",
+			zap.String("user", c.GetString(auth.UsernameKey)),
+			zap.Bool("clearRateLimits", clearRateLimits))
+
+		summary, err := h.Reset()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reload routes", "summary": summary})
+			return
+		}
+		if summary.RateLimitKeysCleared, err = resetMiddleware(clearRateLimits); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset middleware state", "summary": summary})
+			return
+		}
+
+		h.logger.Info("Gateway state reset", zap.Any("summary", summary))
+		c.JSON(http.StatusOK, summary)
+	}
+}
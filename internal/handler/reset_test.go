@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResetClearsCaches(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	route := newTestRoute("/users", backend.URL, http.MethodPost)
+	route.RequestSchema = `{"type":"object"}`
+	route.AdaptiveTimeout = true
+	route.UpstreamProxy = "direct"
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	if resp, _ := p.do(t, newRequest(t, http.MethodPost, "/users", []byte(`{}`))); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d", resp.StatusCode)
+	}
+
+	summary, err := p.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ResetSummary{RoutesLoaded: 1, SchemasCleared: 1, LatencyWindowsCleared: 1, TransportsClosed: 1}
+	if summary != want {
+		t.Fatalf("summary %+v, want %+v", summary, want)
+	}
+
+	if summary, _ := p.Reset(); summary != (ResetSummary{RoutesLoaded: 1}) {
+		t.Fatalf("second reset %+v, want nothing cleared", summary)
+	}
+}
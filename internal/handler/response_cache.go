@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// responseCache keeps backend responses of routes with CacheResponses for as
+// long as their Cache-Control allows. Entries are keyed by method and URI;
+// responses with Vary keep one variant per combination of the varying
+// request headers.
+type responseCache struct {
+	mu         sync.Mutex
+	entries    map[string][]*responseCacheEntry
+	size       int
+	maxEntries int
+}
+
+type responseCacheEntry struct {
+	resp    *CachedResponse
+	vary    map[string]string
+	expires time.Time
+}
+
+func newResponseCache(maxEntries int) *responseCache {
+	return &responseCache{entries: make(map[string][]*responseCacheEntry), maxEntries: maxEntries}
+}
+
+func responseCacheKey(r *http.Request) string {
+	return r.Method + " " + r.URL.RequestURI()
+}
+
+// get returns the fresh response stored for the request, if any.
+func (c *responseCache) get(r *http.Request) *CachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range c.entries[responseCacheKey(r)] {
+		if now.Before(entry.expires) && entry.matches(r) {
+			return entry.resp
+		}
+	}
+	return nil
+}
+
+func (e *responseCacheEntry) matches(r *http.Request) bool {
+	for name, value := range e.vary {
+		if r.Header.Get(name) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// store keeps resp for ttl, replacing the variant with the same Vary values.
+// Responses with "Vary: *" never match a later request and aren't stored.
+func (c *responseCache) store(r *http.Request, resp *CachedResponse, ttl time.Duration) {
+	vary := make(map[string]string)
+	for _, value := range resp.Header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+			if name == "*" {
+				return
+			}
+			if name != "" {
+				vary[name] = r.Header.Get(name)
+			}
+		}
+	}
+	entry := &responseCacheEntry{resp: resp, vary: vary, expires: time.Now().Add(ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := responseCacheKey(r)
+	variants := c.entries[key]
+	for i, existing := range variants {
+		if existing.matches(r) {
+			variants[i] = entry
+			return
+		}
+	}
+
+	// Cheio: descarta as respostas expiradas e, se ainda não houver espaço, não guarda
+	if c.size >= c.maxEntries {
+		c.evictExpired()
+		if c.size >= c.maxEntries {
+			return
+		}
+	}
+	c.entries[key] = append(c.entries[key], entry)
+	c.size++
+}
+
+func (c *responseCache) evictExpired() {
+	now := time.Now()
+	for key, variants := range c.entries {
+		fresh := variants[:0]
+		for _, entry := range variants {
+			if now.Before(entry.expires) {
+				fresh = append(fresh, entry)
+			}
+		}
+		c.size -= len(variants) - len(fresh)
+		if len(fresh) == 0 {
+			delete(c.entries, key)
+		} else {
+			c.entries[key] = fresh
+		}
+	}
+}
+
+func (c *responseCache) reset() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cleared := c.size
+	c.entries = make(map[string][]*responseCacheEntry)
+	c.size = 0
+	return cleared
+}
+
+// responseTTL returns how long a shared cache may keep the response to r
+// according to its Cache-Control (s-maxage over max-age, minus Age). It is
+// zero for responses that mustn't be cached: not 200, no-store, private,
+// no-cache, no max-age, or answers to authenticated requests not marked public.
+func responseTTL(r *http.Request, status int, header http.Header) time.Duration {
+	if status != http.StatusOK {
+		return 0
+	}
+
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			directives[strings.ToLower(name)] = strings.Trim(arg, `"`)
+		}
+	}
+	for _, name := range []string{"no-store", "private", "no-cache"} {
+		if _, ok := directives[name]; ok {
+			return 0
+		}
+	}
+
+	maxAge, hasShared := directives["s-maxage"]
+	if !hasShared {
+		maxAge = directives["max-age"]
+	}
+	seconds, err := strconv.Atoi(maxAge)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+
+	// Respostas a requisições autenticadas só são compartilhadas se o backend permitir explicitamente
+	if _, public := directives["public"]; r.Header.Get("Authorization") != "" && !public && !hasShared {
+		return 0
+	}
+
+	if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+		seconds -= age
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// serveCached answers the request from the response cache when possible, with
+// X-Cache: HIT. Otherwise it calls serve, with X-Cache: MISS, and stores the
+// response if its Cache-Control allows it.
+func (h *Handler) serveCached(w http.ResponseWriter, r *http.Request, serve func(http.ResponseWriter)) {
+	if resp := h.responses.get(r); resp != nil {
+		w.Header().Set("X-Cache", "HIT")
+		// A idade soma o tempo no cache à idade que a resposta já tinha ao chegar do backend
+		age, _ := strconv.Atoi(resp.Header.Get("Age"))
+		w.Header().Set("Age", strconv.Itoa(age+int(time.Since(resp.StoredAt).Seconds())))
+		resp.writeTo(w)
+		return
+	}
+
+	w.Header().Set("X-Cache", "MISS")
+	tee := &teeResponseWriter{ResponseWriter: w, limit: h.cfg.Proxy.ResponseCacheMaxBodyBytes, status: http.StatusOK}
+	serve(tee)
+
+	resp := tee.shared()
+	if resp == nil {
+		return
+	}
+	if ttl := responseTTL(r, resp.Status, resp.Header); ttl > 0 {
+		resp.Header.Del("X-Cache")
+		h.responses.store(r, resp, ttl)
+	}
+}
@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestResponseCacheReplaysOnlyBackendHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("X-Backend", "items")
+		w.Write([]byte("items"))
+	}))
+	defer backend.Close()
+
+	route := newTestRoute("/items", backend.URL)
+	route.CacheResponses = true
+	server := newClientHeaderServer(t, newTestProxy(t, newTestConfig(t), route))
+
+	first, _ := getWithHeaders(t, server.URL+"/items", map[string]string{"X-Request-Id": "first", "Origin": "https://app.example.com"})
+	if got := first.Get("X-Cache"); got != "MISS" {
+		t.Fatalf("first response X-Cache %q, want MISS", got)
+	}
+
+	second, body := getWithHeaders(t, server.URL+"/items", map[string]string{"X-Request-Id": "second"})
+	if got := second.Get("X-Cache"); got != "HIT" {
+		t.Fatalf("second response X-Cache %q, want HIT", got)
+	}
+	if body != "items" || second.Get("X-Backend") != "items" || second.Get("Cache-Control") != "max-age=60" {
+		t.Fatalf("cached response body %q, headers %v; want the backend's", body, second)
+	}
+	if got := second.Get("X-Request-Id"); got != "second" {
+		t.Fatalf("cached response X-Request-Id %q, want the second request's", got)
+	}
+	for _, name := range []string{"Access-Control-Allow-Origin", "Vary"} {
+		if got := second.Values(name); len(got) != 0 {
+			t.Fatalf("cached response replayed the first client's %s %v", name, got)
+		}
+	}
+}
+
+// newCacheableBackend answers with the number of calls so far and the
+// Cache-Control (and Vary) the test asks for in X-Reply-Cache-Control (and
+// X-Reply-Vary), echoing Accept-Language.
+func newCacheableBackend(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	calls := &atomic.Int32{}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Cache-Control", r.Header.Get("X-Reply-Cache-Control"))
+		if vary := r.Header.Get("X-Reply-Vary"); vary != "" {
+			w.Header().Set("Vary", vary)
+		}
+		fmt.Fprintf(w, "call %d %s", n, r.Header.Get("Accept-Language"))
+	}))
+	t.Cleanup(backend.Close)
+	return backend, calls
+}
+
+func newCachingProxy(t *testing.T, backendURL string, maxEntries int) *testProxy {
+	t.Helper()
+	cfg := newTestConfig(t)
+	cfg.Proxy.ResponseCacheMaxEntries = maxEntries
+	route := newTestRoute("/items", backendURL)
+	route.CacheResponses = true
+	return newTestProxy(t, cfg, route)
+}
+
+// cachedGet sends a GET with the given headers and returns X-Cache and the
+// body.
+func (p *testProxy) cachedGet(t *testing.T, path string, header map[string]string) (string, string) {
+	t.Helper()
+	req := newRequest(t, http.MethodGet, path, nil)
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	resp, body := p.do(t, req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, resp.StatusCode)
+	}
+	return resp.Header.Get("X-Cache"), body
+}
+
+func TestResponseCacheControl(t *testing.T) {
+	tests := []struct {
+		name          string
+		cacheControl  string
+		authorization string
+		cached        bool
+	}{
+		{"max-age", "max-age=60", "", true},
+		{"s-maxage", "s-maxage=60, max-age=0", "", true},
+		{"no max-age", "", "", false},
+		{"private", "private, max-age=60", "", false},
+		{"no-store", "no-store, max-age=60", "", false},
+		{"no-cache", "no-cache, max-age=60", "", false},
+		{"authenticated", "max-age=60", "Bearer token", false},
+		{"authenticated and public", "public, max-age=60", "Bearer token", true},
+		{"authenticated with s-maxage", "s-maxage=60", "Bearer token", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, calls := newCacheableBackend(t)
+			p := newCachingProxy(t, backend.URL, 100)
+
+			header := map[string]string{"X-Reply-Cache-Control": tt.cacheControl}
+			if tt.authorization != "" {
+				header["Authorization"] = tt.authorization
+			}
+			first, _ := p.cachedGet(t, "/items", header)
+			second, body := p.cachedGet(t, "/items", header)
+
+			if first != "MISS" {
+				t.Fatalf("first response X-Cache %q, want MISS", first)
+			}
+			wantSecond, wantCalls := "MISS", int32(2)
+			if tt.cached {
+				wantSecond, wantCalls = "HIT", 1
+			}
+			if second != wantSecond || calls.Load() != wantCalls {
+				t.Fatalf("second response X-Cache %q (%q) after %d backend calls, want %s after %d", second, body, calls.Load(), wantSecond, wantCalls)
+			}
+		})
+	}
+}
+
+func TestResponseCacheVaryVariants(t *testing.T) {
+	backend, calls := newCacheableBackend(t)
+	p := newCachingProxy(t, backend.URL, 100)
+
+	steps := []struct {
+		language string
+		xCache   string
+		body     string
+	}{
+		{"en", "MISS", "call 1 en"},
+		{"pt", "MISS", "call 2 pt"},
+		{"en", "HIT", "call 1 en"},
+		{"pt", "HIT", "call 2 pt"},
+	}
+	for i, step := range steps {
+		xCache, body := p.cachedGet(t, "/items", map[string]string{
+			"X-Reply-Cache-Control": "max-age=60",
+			"X-Reply-Vary":          "Accept-Language",
+			"Accept-Language":       step.language,
+		})
+		if xCache != step.xCache || body != step.body {
+			t.Fatalf("request %d (%s): X-Cache %q, body %q; want %s, %q", i+1, step.language, xCache, body, step.xCache, step.body)
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Fatalf("backend called %d times for 2 variants, want 2", n)
+	}
+}
+
+func TestResponseCacheEntryCap(t *testing.T) {
+	backend, _ := newCacheableBackend(t)
+	p := newCachingProxy(t, backend.URL, 2)
+
+	header := map[string]string{"X-Reply-Cache-Control": "max-age=60"}
+	for _, path := range []string{"/items?page=1", "/items?page=2", "/items?page=3"} {
+		if xCache, _ := p.cachedGet(t, path, header); xCache != "MISS" {
+			t.Fatalf("first GET %s: X-Cache %q, want MISS", path, xCache)
+		}
+	}
+
+	// Cheio com respostas ainda válidas: a terceira não foi guardada, as duas primeiras continuam
+	for path, want := range map[string]string{"/items?page=1": "HIT", "/items?page=2": "HIT", "/items?page=3": "MISS"} {
+		if xCache, _ := p.cachedGet(t, path, header); xCache != want {
+			t.Fatalf("second GET %s: X-Cache %q, want %s", path, xCache, want)
+		}
+	}
+	if size := p.responses.reset(); size != 2 {
+		t.Fatalf("%d entries cached, want the cap (2)", size)
+	}
+}
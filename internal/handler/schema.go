@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// bodylessMethods are never validated against the request schema, so a route
+// can serve GET and POST with a schema for the POST body.
+var bodylessMethods = []string{http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodDelete}
+
+type SchemaViolation struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// schemaValidator compiles route request schemas once and counts, per route,
+// the requests rejected for not matching them.
+type schemaValidator struct {
+	mu       sync.Mutex
+	compiled map[string]*compiledSchema
+	failures map[string]int64
+}
+
+// compiledSchema is the schema compiled for a route and the source it was
+// compiled from.
+type compiledSchema struct {
+	source string
+	schema *jsonschema.Schema
+}
+
+func newSchemaValidator() *schemaValidator {
+	return &schemaValidator{
+		compiled: make(map[string]*compiledSchema),
+		failures: make(map[string]int64),
+	}
+}
+
+// compile returns the compiled schema of the route at path. It is recompiled,
+// replacing the previous one, when the route's schema source changes.
+func (v *schemaValidator) compile(path, source string) (*jsonschema.Schema, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if cached, ok := v.compiled[path]; ok && cached.source == source {
+		return cached.schema, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = refuseExternalSchema
+	if err := compiler.AddResource("schema.json", strings.NewReader(source)); err != nil {
+		return nil, err
+	}
+	schema, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, err
+	}
+	v.compiled[path] = &compiledSchema{source: source, schema: schema}
+	return schema, nil
+}
+
+// refuseExternalSchema keeps a route schema from reading local files or
+// remote documents through $ref; schemas must be self-contained.
+func refuseExternalSchema(url string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("external schema reference %s is not allowed", url)
+}
+
+func (v *schemaValidator) reset() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	cleared := len(v.compiled)
+	v.compiled = make(map[string]*compiledSchema)
+	return cleared
+}
+
+func (v *schemaValidator) recordFailure(path string) {
+	v.mu.Lock()
+	v.failures[path]++
+	v.mu.Unlock()
+}
+
+func (v *schemaValidator) failureCount(path string) int64 {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.failures[path]
+}
+
+// validateRequestSchema checks the request body against the route schema and
+// writes the error response itself, returning false, when it doesn't match.
+// Requests without a body are not validated.
+func (h *Handler) validateRequestSchema(w http.ResponseWriter, r *http.Request, route *config.Route) bool {
+	if config.MethodIn(r.Method, bodylessMethods) || r.ContentLength == 0 {
+		return true
+	}
+
+	schema, err := h.schemas.compile(route.Path, route.RequestSchema)
+	if err != nil {
+		h.logger.Error("Invalid request schema", zap.String("path", route.Path), zap.Error(err))
+		h.writeError(w, r, http.StatusInternalServerError, "Internal server error")
+		return false
+	}
+
+	body, buffered, err := bufferBody(r, h.cfg.Proxy.SchemaMaxBodyBytes)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, "Failed to read request body")
+		return false
+	}
+	if !buffered {
+		h.writeError(w, r, http.StatusRequestEntityTooLarge, "Request body too large to validate")
+		return false
+	}
+	// Body chunked que chegou vazio
+	if len(body) == 0 {
+		return true
+	}
+
+	var violations []SchemaViolation
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		violations = []SchemaViolation{{Field: "", Message: "invalid JSON: " + err.Error()}}
+	} else if err := schema.Validate(document); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if !errors.As(err, &validationErr) {
+			h.logger.Error("Schema validation failed", zap.String("path", route.Path), zap.Error(err))
+			h.writeError(w, r, http.StatusInternalServerError, "Internal server error")
+			return false
+		}
+		for _, cause := range validationErr.BasicOutput().Errors {
+			// A primeira entrada é o resumo do erro na raiz, sem detalhe útil
+			if cause.KeywordLocation == "" {
+				continue
+			}
+			violations = append(violations, SchemaViolation{Field: cause.InstanceLocation, Message: cause.Error})
+		}
+	}
+
+	if violations == nil {
+		return true
+	}
+
+	h.schemas.recordFailure(route.Path)
+	h.logger.Info("schema_validation_failed", zap.String("path", route.Path), zap.Int("violations", len(violations)))
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   "Request body does not match schema",
+		"details": violations,
+	})
+	return false
+}
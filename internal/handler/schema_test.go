@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequestSchema(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	route := newTestRoute("/users", backend.URL, http.MethodGet, http.MethodPost)
+	route.RequestSchema = `{"type":"object","required":["name"],"properties":{"name":{"type":"string"}}}`
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		want   int
+	}{
+		{"GET without body", http.MethodGet, "", http.StatusOK},
+		{"POST without body", http.MethodPost, "", http.StatusOK},
+		{"POST matching schema", http.MethodPost, `{"name":"ana"}`, http.StatusOK},
+		{"POST missing field", http.MethodPost, `{}`, http.StatusBadRequest},
+		{"POST invalid JSON", http.MethodPost, `{`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(t, tt.method, "/users", []byte(tt.body))
+			if resp, body := p.do(t, req); resp.StatusCode != tt.want {
+				t.Fatalf("status %d (%s), want %d", resp.StatusCode, body, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestSchemaUpdateReplacesCompiled(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	route := newTestRoute("/users", backend.URL, http.MethodPost)
+	route.RequestSchema = `{"$schema":"http://json-schema.org/draft-07/schema#","type":"object","required":["name"]}`
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	post := func(body string) int {
+		t.Helper()
+		resp, _ := p.do(t, newRequest(t, http.MethodPost, "/users", []byte(body)))
+		return resp.StatusCode
+	}
+	if status := post(`{"email":"ana@example.com"}`); status != http.StatusBadRequest {
+		t.Fatalf("body without name: status %d, want 400", status)
+	}
+
+	update := fmt.Sprintf(`{"path":"/users","serviceURL":%q,"methods":["POST"],"isActive":true,"version":1,"requestSchema":%q}`,
+		backend.URL, `{"type":"object","required":["email"]}`)
+	if resp, body := p.do(t, newRequest(t, http.MethodPut, "/admin/update", []byte(update))); resp.StatusCode != http.StatusOK {
+		t.Fatalf("update: status %d (%s)", resp.StatusCode, body)
+	}
+	if status := post(`{"email":"ana@example.com"}`); status != http.StatusOK {
+		t.Fatalf("body matching the new schema: status %d, want 200", status)
+	}
+	if status := post(`{"name":"ana"}`); status != http.StatusBadRequest {
+		t.Fatalf("body matching only the old schema: status %d, want 400", status)
+	}
+
+	// A versão antiga do schema não fica para trás no cache
+	p.schemas.mu.Lock()
+	compiled := len(p.schemas.compiled)
+	p.schemas.mu.Unlock()
+	if compiled != 1 {
+		t.Fatalf("%d compiled schemas kept for one route, want 1", compiled)
+	}
+}
+
+func TestRequestSchemaRefusesFileRef(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	external := filepath.Join(t.TempDir(), "external.json")
+	if err := os.WriteFile(external, []byte(`{"type":"object"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	route := newTestRoute("/users", backend.URL, http.MethodPost)
+	route.RequestSchema = fmt.Sprintf(`{"$ref":"file://%s"}`, external)
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	resp, body := p.do(t, newRequest(t, http.MethodPost, "/users", []byte(`{}`)))
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("schema with file $ref: status %d (%s), want 500", resp.StatusCode, body)
+	}
+}
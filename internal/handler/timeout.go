@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	latencyWindowSize = 200
+	// p99Interval recalcula o p99 a cada N amostras em vez de ordenar a janela a cada resposta
+	p99Interval = 10
+)
+
+// latencyWindow keeps the most recent backend latencies of a route.
+type latencyWindow struct {
+	samples [latencyWindowSize]time.Duration
+	next    int
+	count   int
+	added   int
+	p99     time.Duration
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % latencyWindowSize
+	if w.count < latencyWindowSize {
+		w.count++
+	}
+	w.added++
+	if w.added%p99Interval == 0 || w.p99 == 0 {
+		w.p99 = percentile(w.samples[:w.count], 0.99)
+	}
+}
+
+func percentile(samples []time.Duration, p float64) time.Duration {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+// latencyTracker keeps a rolling p99 of backend latency per route, used to
+// derive adaptive request timeouts.
+type latencyTracker struct {
+	mu      sync.Mutex
+	windows map[string]*latencyWindow
+}
+
+func newLatencyTracker() *latencyTracker {
+	return &latencyTracker{windows: make(map[string]*latencyWindow)}
+}
+
+func (l *latencyTracker) record(path string, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[path]
+	if !ok {
+		w = &latencyWindow{}
+		l.windows[path] = w
+	}
+	w.add(d)
+}
+
+func (l *latencyTracker) reset() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cleared := len(l.windows)
+	l.windows = make(map[string]*latencyWindow)
+	return cleared
+}
+
+// p99 returns the rolling p99 of a route and how many samples it is based on.
+func (l *latencyTracker) p99(path string) (time.Duration, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[path]
+	if !ok {
+		return 0, 0
+	}
+	return w.p99, w.count
+}
+
+// requestTimeout returns the timeout for a backend call to the route: a
+// multiple of its p99 latency, bounded by the configured min/max, when the
+// route uses adaptive timeouts and enough samples exist; otherwise the
+// route's own timeout or the global one. Zero means no timeout.
+func (h *Handler) requestTimeout(route *config.Route) time.Duration {
+	cfg := h.cfg.Proxy
+	static := cfg.RequestTimeout
+	if route.Timeout > 0 {
+		static = route.Timeout
+	}
+	if !route.AdaptiveTimeout {
+		return static
+	}
+
+	p99, samples := h.latencies.p99(route.Path)
+	if samples < cfg.AdaptiveTimeoutMinSamples {
+		return static
+	}
+
+	timeout := time.Duration(float64(p99) * cfg.AdaptiveTimeoutMultiplier)
+	if timeout < cfg.AdaptiveTimeoutMin {
+		timeout = cfg.AdaptiveTimeoutMin
+	}
+	if timeout > cfg.AdaptiveTimeoutMax {
+		timeout = cfg.AdaptiveTimeoutMax
+	}
+	return timeout
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveTimeoutGrowsAfterTimeouts(t *testing.T) {
+	var delay atomic.Int64
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Duration(delay.Load()))
+	}))
+	defer backend.Close()
+
+	cfg := newTestConfig(t)
+	cfg.Proxy.AdaptiveTimeoutMultiplier = 2
+	cfg.Proxy.AdaptiveTimeoutMinSamples = 10
+	cfg.Proxy.AdaptiveTimeoutMin = 20 * time.Millisecond
+	cfg.Proxy.AdaptiveTimeoutMax = 2 * time.Second
+
+	route := newTestRoute("/slow", backend.URL)
+	route.AdaptiveTimeout = true
+	p := newTestProxy(t, cfg, route)
+
+	// Backend rápido: o timeout cai para o mínimo
+	for i := 0; i < 10; i++ {
+		if resp, _ := p.get(t, "/slow"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("fast request %d: status %d", i+1, resp.StatusCode)
+		}
+	}
+	if timeout := p.requestTimeout(route); timeout != cfg.Proxy.AdaptiveTimeoutMin {
+		t.Fatalf("timeout %v after fast responses, want %v", timeout, cfg.Proxy.AdaptiveTimeoutMin)
+	}
+
+	// Backend mais lento que o timeout atual: os timeouts entram na janela e o timeout cresce até cobri-lo
+	delay.Store(int64(60 * time.Millisecond))
+	timedOut := 0
+	for i := 0; i < 50; i++ {
+		resp, _ := p.get(t, "/slow")
+		if resp.StatusCode == http.StatusOK {
+			if timedOut == 0 {
+				t.Fatal("slow backend answered within the minimum timeout")
+			}
+			return
+		}
+		if resp.StatusCode != http.StatusGatewayTimeout {
+			t.Fatalf("slow request %d: status %d", i+1, resp.StatusCode)
+		}
+		timedOut++
+	}
+	t.Fatalf("still timing out after %d requests, timeout %v", timedOut, p.requestTimeout(route))
+}
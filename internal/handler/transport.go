@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"golang.org/x/net/http/httpproxy"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// newTransport builds the transport used to reach backends, trusting the given
+// CA bundle in addition to (or instead of) the system roots.
+func newTransport(caCertFile, caCertPEM string, appendSystem bool) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caCertFile == "" && caCertPEM == "" {
+		return transport, nil
+	}
+
+	pool := x509.NewCertPool()
+	if appendSystem {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system cert pool: %w", err)
+		}
+		pool = systemPool
+	}
+
+	if caCertFile != "" {
+		pem, err := os.ReadFile(caCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA cert file: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", caCertFile)
+		}
+	}
+
+	if caCertPEM != "" && !pool.AppendCertsFromPEM([]byte(caCertPEM)) {
+		return nil, errors.New("no valid certificates found in CA cert PEM")
+	}
+
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return transport, nil
+}
+
+// proxyFunc returns the Transport.Proxy function for an upstream proxy URL.
+// An empty URL keeps the HTTP_PROXY/HTTPS_PROXY/NO_PROXY environment and
+// "direct" disables proxying. Hosts matching noProxy (NO_PROXY syntax) are
+// reached directly.
+func proxyFunc(proxyURL string, noProxy []string) (func(*http.Request) (*url.URL, error), error) {
+	switch proxyURL {
+	case "":
+		return http.ProxyFromEnvironment, nil
+	case config.UpstreamProxyDirect:
+		return nil, nil
+	}
+	if err := config.ValidateUpstreamProxy(proxyURL); err != nil {
+		return nil, err
+	}
+
+	proxyForURL := (&httpproxy.Config{
+		HTTPProxy:  proxyURL,
+		HTTPSProxy: proxyURL,
+		NoProxy:    strings.Join(noProxy, ","),
+	}).ProxyFunc()
+	return func(r *http.Request) (*url.URL, error) {
+		return proxyForURL(r.URL)
+	}, nil
+}
+
+// transportFor returns the transport for a route, building and caching one
+// when the route brings its own CA bundle or upstream proxy. Settings the
+// route doesn't override come from the global proxy configuration. A route
+// whose transport can't be built uses the default one; the failure is cached
+// so it is logged once, not on every request.
+func (h *Handler) transportFor(route *config.Route) http.RoundTripper {
+	if route.CACertFile == "" && route.UpstreamProxy == "" {
+		return h.transport
+	}
+
+	key := route.CACertFile + "|" + route.UpstreamProxy
+
+	h.transportsMu.Lock()
+	defer h.transportsMu.Unlock()
+
+	if t, ok := h.routeTransports[key]; ok {
+		// nil registra uma falha anterior ao montar o transporte desta rota
+		if t == nil {
+			return h.transport
+		}
+		return t
+	}
+
+	t, err := h.newRouteTransport(route)
+	if err != nil {
+		h.logger.Error("Failed to build route transport, using default", zap.String("path", route.Path), zap.Error(err))
+		h.routeTransports[key] = nil
+		return h.transport
+	}
+	h.routeTransports[key] = t
+	return t
+}
+
+// resetTransports drops the per-route transports, rebuilt on next use, and
+// closes the idle connections of every transport. It returns how many route
+// transports were closed.
+func (h *Handler) resetTransports() int {
+	h.transportsMu.Lock()
+	defer h.transportsMu.Unlock()
+
+	closed := 0
+	for _, t := range h.routeTransports {
+		if t != nil {
+			t.CloseIdleConnections()
+			closed++
+		}
+	}
+	h.routeTransports = make(map[string]*http.Transport)
+	h.transport.CloseIdleConnections()
+	return closed
+}
+
+func (h *Handler) newRouteTransport(route *config.Route) (*http.Transport, error) {
+	// O bundle da rota substitui o global; sem bundle próprio ela segue o global
+	caCertFile, caCertPEM := route.CACertFile, ""
+	if caCertFile == "" {
+		caCertFile, caCertPEM = h.cfg.Proxy.CACertFile, h.cfg.Proxy.CACertPEM
+	}
+	t, err := newTransport(caCertFile, caCertPEM, h.cfg.Proxy.CAAppendSystem)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sem proxy próprio a rota segue o proxy global
+	upstreamProxy := route.UpstreamProxy
+	if upstreamProxy == "" {
+		upstreamProxy = h.cfg.Proxy.UpstreamProxy
+	}
+	if t.Proxy, err = proxyFunc(upstreamProxy, h.cfg.Proxy.NoProxy); err != nil {
+		return nil, fmt.Errorf("invalid upstream proxy: %w", err)
+	}
+
+	t.DialContext = h.dns.dialFunc()
+	return t, nil
+}
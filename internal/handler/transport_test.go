@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/pem"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteUpstreamProxy(t *testing.T) {
+	var proxiedURL string
+	stub := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Um proxy HTTP recebe a URL absoluta do destino
+		proxiedURL = r.URL.String()
+		w.Write([]byte("via proxy"))
+	}))
+	defer stub.Close()
+
+	route := newTestRoute("/users", "http://users.internal")
+	route.UpstreamProxy = stub.URL
+	p := newTestProxy(t, newTestConfig(t), route)
+
+	resp, body := p.get(t, "/users")
+	if resp.StatusCode != http.StatusOK || body != "via proxy" {
+		t.Fatalf("got %d %q, want the stub proxy response", resp.StatusCode, body)
+	}
+	if proxiedURL != "http://users.internal/users" {
+		t.Fatalf("proxy received %q", proxiedURL)
+	}
+}
+
+func TestRouteTransportKeepsGlobalCA(t *testing.T) {
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("private CA"))
+	}))
+	defer backend.Close()
+
+	cfg := newTestConfig(t)
+	cfg.Proxy.CACertPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: backend.Certificate().Raw}))
+	cfg.Proxy.CAAppendSystem = false
+
+	// A rota só troca o proxy; o bundle global de CAs continua valendo
+	route := newTestRoute("/private", backend.URL)
+	route.UpstreamProxy = "direct"
+	p := newTestProxy(t, cfg, route)
+
+	if resp, body := p.get(t, "/private"); resp.StatusCode != http.StatusOK || body != "private CA" {
+		t.Fatalf("got %d %q, want the backend response over TLS", resp.StatusCode, body)
+	}
+}
+
+func TestRouteTransportFailureLoggedOnce(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+
+	core, logs := observer.New(zap.ErrorLevel)
+	route := newTestRoute("/broken", backend.URL)
+	route.CACertFile = "/nonexistent/ca.pem"
+	p := newTestProxyWithLogger(t, zap.New(core), newTestConfig(t), route)
+
+	for i := 0; i < 3; i++ {
+		if resp, _ := p.get(t, "/broken"); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status %d, want the default transport to serve it", i+1, resp.StatusCode)
+		}
+	}
+	if n := logs.FilterMessage("Failed to build route transport, using default").Len(); n != 1 {
+		t.Fatalf("transport failure logged %d times, want 1", n)
+	}
+}
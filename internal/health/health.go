@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/internal/workers"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errCheckTimeout is reported for checks still running when the readiness
+// budget runs out.
+var errCheckTimeout = errors.New("check did not finish within the readiness budget")
+
+// dependencyCheck is a readiness check of one dependency. check must return
+// once ctx is done.
+type dependencyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type HealthChecker struct {
+	db           *database.Database
+	logger       *zap.Logger
+	shuttingDown atomic.Bool
+	ready        atomic.Bool
+	history      checkHistory
+
+	// concurrency limita as verificações simultâneas e timeout é o prazo total do probe
+	concurrency int
+	timeout     time.Duration
+
+	checksMu sync.RWMutex
+	checks   []dependencyCheck
+}
+
+func NewHealthChecker(db *database.Database, logger *zap.Logger, concurrency int, timeout time.Duration) *HealthChecker {
+	return &HealthChecker{db: db, logger: logger, concurrency: concurrency, timeout: timeout}
+}
+
+// AddCheck registers a dependency checked by the readiness probe, alongside
+// the database. A failing check makes the gateway report DOWN.
+func (h *HealthChecker) AddCheck(name string, check func(ctx context.Context) error) {
+	h.checksMu.Lock()
+	defer h.checksMu.Unlock()
+	h.checks = append(h.checks, dependencyCheck{name: name, check: check})
+}
+
+// StartShutdown makes the readiness probe report DOWN so load balancers stop
+// sending new requests while in-flight ones drain.
+func (h *HealthChecker) StartShutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// MarkReady makes the readiness probe report UP. Until it is called, after the
+// routes are loaded, the gateway reports DOWN so no traffic arrives early.
+func (h *HealthChecker) MarkReady() {
+	h.ready.Store(true)
+}
+
+func (h *HealthChecker) LivenessCheck(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "UP"})
+}
+
+func (h *HealthChecker) ReadinessCheck(c *gin.Context) {
+	if h.shuttingDown.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "DOWN", "reason": "shutting down"})
+		return
+	}
+	if !h.ready.Load() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "DOWN", "reason": "starting"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.timeout)
+	defer cancel()
+
+	// A contagem também valida o acesso ao banco sem carregar todas as rotas
+	var activeRoutes int
+	checks := []dependencyCheck{{name: "database", check: func(ctx context.Context) error {
+		var err error
+		activeRoutes, err = h.db.CountActiveRoutes(ctx)
+		return err
+	}}}
+	h.checksMu.RLock()
+	checks = append(checks, h.checks...)
+	h.checksMu.RUnlock()
+
+	errs := h.runChecks(ctx, checks)
+	for i, err := range errs {
+		if err != nil {
+			h.logger.Warn("Readiness check failed", zap.String("dependency", checks[i].name), zap.Error(err))
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "DOWN", "reason": checks[i].name + " unavailable"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "UP", "activeRoutes": activeRoutes})
+}
+
+// runChecks runs the checks in parallel, at most h.concurrency at a time, and
+// returns their errors in order. Checks that haven't finished when ctx is done
+// are reported as timed out; the probe doesn't wait for them.
+func (h *HealthChecker) runChecks(ctx context.Context, checks []dependencyCheck) []error {
+	var (
+		mu       sync.Mutex
+		finished = make([]bool, len(checks))
+		errs     = make([]error, len(checks))
+		done     = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		workers.Run(h.concurrency, len(checks), func(i int) {
+			if ctx.Err() != nil {
+				return
+			}
+			start := time.Now()
+			err := checks[i].check(ctx)
+			h.history.record(checks[i].name, start, err)
+
+			mu.Lock()
+			finished[i], errs[i] = true, err
+			mu.Unlock()
+		}, nil)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	results := make([]error, len(checks))
+	for i := range checks {
+		if finished[i] {
+			results[i] = errs[i]
+		} else {
+			results[i] = errCheckTimeout
+		}
+	}
+	return results
+}
@@ -0,0 +1,148 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestChecker(t *testing.T, concurrency int, timeout time.Duration) (*HealthChecker, *gin.Engine) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "routes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHealthChecker(db, zap.NewNop(), concurrency, timeout)
+
+	r := gin.New()
+	r.GET("/health/live", h.LivenessCheck)
+	r.GET("/health/ready", h.ReadinessCheck)
+	return h, r
+}
+
+// probe calls the endpoint and returns its status and decoded body.
+func probe(t *testing.T, r *gin.Engine, path string) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("%s: invalid body %q: %v", path, w.Body.String(), err)
+	}
+	return w.Code, body
+}
+
+func TestReadinessBeforeReady(t *testing.T) {
+	h, r := newTestChecker(t, 4, time.Second)
+
+	// Antes de MarkReady o processo está vivo, mas não deve receber tráfego
+	if status, _ := probe(t, r, "/health/live"); status != http.StatusOK {
+		t.Fatalf("liveness before ready: status %d, want 200", status)
+	}
+	status, body := probe(t, r, "/health/ready")
+	if status != http.StatusServiceUnavailable || body["reason"] != "starting" {
+		t.Fatalf("readiness before ready: got %d %v, want 503 starting", status, body)
+	}
+
+	h.MarkReady()
+	if status, body := probe(t, r, "/health/ready"); status != http.StatusOK || body["status"] != "UP" {
+		t.Fatalf("readiness after ready: got %d %v, want 200 UP", status, body)
+	}
+
+	h.StartShutdown()
+	if status, body := probe(t, r, "/health/ready"); status != http.StatusServiceUnavailable || body["reason"] != "shutting down" {
+		t.Fatalf("readiness during shutdown: got %d %v, want 503 shutting down", status, body)
+	}
+}
+
+func TestReadinessManyDependencies(t *testing.T) {
+	const concurrency = 4
+	h, r := newTestChecker(t, concurrency, 5*time.Second)
+	h.MarkReady()
+
+	var (
+		mu            sync.Mutex
+		running, peak int
+		completed     atomic.Int32
+	)
+	for i := 0; i < 50; i++ {
+		h.AddCheck(fmt.Sprintf("backend-%d", i), func(ctx context.Context) error {
+			mu.Lock()
+			running++
+			if running > peak {
+				peak = running
+			}
+			mu.Unlock()
+
+			time.Sleep(2 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			completed.Add(1)
+			return nil
+		})
+	}
+
+	if status, body := probe(t, r, "/health/ready"); status != http.StatusOK {
+		t.Fatalf("status %d %v, want 200", status, body)
+	}
+	if n := completed.Load(); n != 50 {
+		t.Fatalf("%d dependency checks ran, want 50", n)
+	}
+	if peak > concurrency {
+		t.Fatalf("%d checks ran at once, want at most %d", peak, concurrency)
+	}
+}
+
+func TestReadinessDeadline(t *testing.T) {
+	h, r := newTestChecker(t, 2, 100*time.Millisecond)
+	h.MarkReady()
+
+	var started atomic.Int32
+	for i := 0; i < 20; i++ {
+		h.AddCheck(fmt.Sprintf("slow-%d", i), func(ctx context.Context) error {
+			started.Add(1)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}
+
+	begin := time.Now()
+	status, body := probe(t, r, "/health/ready")
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("probe took %v, want it bounded by the 100ms budget", elapsed)
+	}
+	if status != http.StatusServiceUnavailable || body["reason"] != "slow-0 unavailable" {
+		t.Fatalf("got %d %v, want 503 slow-0 unavailable", status, body)
+	}
+	// Com o prazo esgotado as verificações restantes nem começam
+	if n := started.Load(); n > 2 {
+		t.Fatalf("%d slow checks started, want at most the 2 concurrent ones", n)
+	}
+}
+
+func TestReadinessFailingDependency(t *testing.T) {
+	h, r := newTestChecker(t, 4, time.Second)
+	h.MarkReady()
+	h.AddCheck("cache", func(context.Context) error { return nil })
+	h.AddCheck("billing", func(context.Context) error { return errors.New("connection refused") })
+
+	status, body := probe(t, r, "/health/ready")
+	if status != http.StatusServiceUnavailable || body["reason"] != "billing unavailable" {
+		t.Fatalf("got %d %v, want 503 billing unavailable", status, body)
+	}
+}
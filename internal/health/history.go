@@ -0,0 +1,105 @@
+package health
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// historySize is how many checks are kept per dependency.
+const historySize = 30
+
+type CheckResult struct {
+	Time    time.Time     `json:"time"`
+	Latency time.Duration `json:"latency"`
+	OK      bool          `json:"ok"`
+	Error   string        `json:"error,omitempty"`
+}
+
+type DependencyHistory struct {
+	Name        string        `json:"name"`
+	Checks      []CheckResult `json:"checks"`
+	Failures    int           `json:"failures"`
+	AvgLatency  time.Duration `json:"avgLatency"`
+	LastLatency time.Duration `json:"lastLatency"`
+}
+
+// checkRing holds the last historySize checks of one dependency.
+type checkRing struct {
+	entries [historySize]CheckResult
+	next    int
+	full    bool
+}
+
+func (r *checkRing) add(result CheckResult) {
+	r.entries[r.next] = result
+	r.next = (r.next + 1) % historySize
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// ordered returns the checks from oldest to newest.
+func (r *checkRing) ordered() []CheckResult {
+	if !r.full {
+		return append([]CheckResult(nil), r.entries[:r.next]...)
+	}
+	return append(append([]CheckResult(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+type checkHistory struct {
+	mu           sync.Mutex
+	dependencies map[string]*checkRing
+}
+
+func (h *checkHistory) record(name string, start time.Time, err error) {
+	result := CheckResult{Time: start.UTC(), Latency: time.Since(start), OK: err == nil}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.dependencies == nil {
+		h.dependencies = make(map[string]*checkRing)
+	}
+	ring, ok := h.dependencies[name]
+	if !ok {
+		ring = &checkRing{}
+		h.dependencies[name] = ring
+	}
+	ring.add(result)
+}
+
+func (h *checkHistory) snapshot() []DependencyHistory {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	histories := make([]DependencyHistory, 0, len(h.dependencies))
+	for name, ring := range h.dependencies {
+		history := DependencyHistory{Name: name, Checks: ring.ordered()}
+		var total time.Duration
+		for _, check := range history.Checks {
+			total += check.Latency
+			if !check.OK {
+				history.Failures++
+			}
+		}
+		if n := len(history.Checks); n > 0 {
+			history.AvgLatency = total / time.Duration(n)
+			history.LastLatency = history.Checks[n-1].Latency
+		}
+		histories = append(histories, history)
+	}
+	sort.Slice(histories, func(i, j int) bool { return histories[i].Name < histories[j].Name })
+	return histories
+}
+
+// DependencyHistory returns the latency and outcome of the last readiness
+// checks of each dependency, to tell a consistently slow dependency from a
+// one-off blip.
+func (h *HealthChecker) DependencyHistory(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"dependencies": h.history.snapshot()})
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/internal/auth"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticateAdmin(t *testing.T) {
+	token, err := auth.GenerateJWT("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tokens emitidos antes do revoke-all do usuário são recusados
+	revoked, err := auth.GenerateJWT("revoked-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.RevokeUser("revoked-admin")
+
+	tests := []struct {
+		name           string
+		authEnabled    bool
+		allowAnonymous bool
+		authorization  string
+		status         int
+	}{
+		{"auth disabled, anonymous admin not allowed", false, false, "", http.StatusUnauthorized},
+		{"auth disabled, anonymous admin allowed", false, true, "", http.StatusOK},
+		{"auth disabled, admin token", false, false, "Bearer " + token, http.StatusOK},
+		{"auth enabled ignores anonymous admin", true, true, "", http.StatusUnauthorized},
+		{"auth enabled, admin token", true, false, "Bearer " + token, http.StatusOK},
+		{"auth enabled, invalid token", true, false, "Bearer not-a-token", http.StatusUnauthorized},
+		{"auth enabled, revoked token", true, false, "Bearer " + revoked, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			cfg.Auth.Enabled = tt.authEnabled
+			cfg.Auth.AllowAnonymousAdmin = tt.allowAnonymous
+			_, mw, _ := newTestProxy(t, cfg)
+
+			r := gin.New()
+			r.GET("/admin/apis", mw.AuthenticateAdmin, func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/apis", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status %d (%s), want %d", w.Code, w.Body.String(), tt.status)
+			}
+		})
+	}
+}
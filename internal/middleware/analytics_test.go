@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAnalyticsCountsChunkedResponse(t *testing.T) {
+	chunks := []string{"first chunk;", "second chunk;", "last chunk"}
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Sem Content-Length e com flush entre as partes, a resposta sai chunked
+		for _, chunk := range chunks {
+			io.WriteString(w, chunk)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer backend.Close()
+
+	g := newTestGateway(t, newTestConfig(t), &config.Route{Path: "/stream", ServiceURL: backend.URL, Methods: []string{http.MethodGet}, IsActive: true})
+	core, logs := observer.New(zap.InfoLevel)
+	g.mw.logger = zap.New(core)
+
+	resp, err := http.Get(g.server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join(chunks, "")
+	if string(body) != want {
+		t.Fatalf("body %q, want %q", body, want)
+	}
+	if resp.ContentLength != -1 || len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Fatalf("Content-Length %d, Transfer-Encoding %v; want the response still chunked", resp.ContentLength, resp.TransferEncoding)
+	}
+
+	processed := logs.FilterMessage("Request processed").All()
+	if len(processed) != 1 {
+		t.Fatalf("%d access log entries, want 1", len(processed))
+	}
+	if size := processed[0].ContextMap()["responseSize"]; size != int64(len(want)) {
+		t.Fatalf("responseSize %v, want the %d bytes written", size, len(want))
+	}
+}
+
+func TestAnalyticsCountsConcurrentCalls(t *testing.T) {
+	backend := newTestBackend(t)
+	g := newTestGateway(t, newTestConfig(t), &config.Route{Path: "/items", ServiceURL: backend.URL, Methods: []string{http.MethodGet}, IsActive: true})
+
+	// Poucas requisições para não chegar ao limite por IP
+	const requests = 8
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get(g.server.URL + "/items")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	route, _ := g.mw.routes.Get("/items")
+	g.waitForCalls(t, route, requests)
+}
+
+// waitForCalls waits for Analytics, which runs after the response is written,
+// to count want calls on route.
+func (g *testGateway) waitForCalls(t *testing.T, route *config.Route, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		callCount, _ := g.mw.routes.CallMetrics(route)
+		if callCount == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("call count %d, want %d", callCount, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
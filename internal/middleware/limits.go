@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"sync"
+)
+
+// Reasons counted when a request is rejected by a size or load limit.
+const (
+	LimitPathLength  = "path_length"
+	LimitHeaderCount = "header_count"
+	LimitHeaderSize  = "header_size"
+	LimitInFlight    = "global_overload"
+)
+
+// overloadRetryAfter is the Retry-After, in seconds, sent with requests shed
+// by MaxInFlight.
+const overloadRetryAfter = "1"
+
+// limitRejections counts the requests rejected by each limit.
+var limitRejections = struct {
+	sync.Mutex
+	counts map[string]int64
+}{counts: make(map[string]int64)}
+
+func countLimitRejection(reason string) {
+	limitRejections.Lock()
+	limitRejections.counts[reason]++
+	limitRejections.Unlock()
+}
+
+// LimitRejections returns how many requests each size or load limit has rejected.
+func LimitRejections(c *gin.Context) {
+	limitRejections.Lock()
+	counts := make(map[string]int64, len(limitRejections.counts))
+	for reason, count := range limitRejections.counts {
+		counts[reason] = count
+	}
+	limitRejections.Unlock()
+
+	c.JSON(http.StatusOK, counts)
+}
+
+// MaxPathLength rejects requests whose path exceeds max bytes with 414 before
+// any route lookup happens. A max of zero disables the check.
+func MaxPathLength(max int, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if max > 0 && len(c.Request.URL.Path) > max {
+			logger.Warn("Request path too long",
+				zap.Int("length", len(c.Request.URL.Path)),
+				zap.Int("max", max),
+				zap.String("clientIP", c.ClientIP()))
+			countLimitRejection(LimitPathLength)
+			c.AbortWithStatusJSON(http.StatusRequestURITooLong, gin.H{"error": "URI Too Long"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// MaxHeaders rejects requests with more than maxCount header lines or more
+// than maxBytes of headers with 431 before any route lookup happens. Zero
+// disables the respective check.
+func MaxHeaders(maxCount, maxBytes int, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count, size := 0, 0
+		for name, values := range c.Request.Header {
+			for _, value := range values {
+				count++
+				// nome + ": " + valor + CRLF, como na requisição original
+				size += len(name) + len(value) + 4
+			}
+		}
+
+		reason := ""
+		switch {
+		case maxCount > 0 && count > maxCount:
+			reason = LimitHeaderCount
+		case maxBytes > 0 && size > maxBytes:
+			reason = LimitHeaderSize
+		}
+		if reason != "" {
+			logger.Warn("Request headers too large",
+				zap.String("reason", reason),
+				zap.Int("count", count),
+				zap.Int("bytes", size),
+				zap.String("clientIP", c.ClientIP()))
+			countLimitRejection(reason)
+			c.AbortWithStatusJSON(http.StatusRequestHeaderFieldsTooLarge, gin.H{"error": "Request Header Fields Too Large"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// MaxInFlight sheds requests with 503 and Retry-After while max requests are
+// already being processed, before authentication and route lookup run. A max
+// of zero disables the limit.
+func MaxInFlight(max int, logger *zap.Logger) gin.HandlerFunc {
+	if max <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	slots := make(chan struct{}, max)
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			// Debug para não transformar uma enxurrada de requisições em uma enxurrada de logs
+			logger.Debug("Request shed, gateway overloaded",
+				zap.Int("maxInFlight", max),
+				zap.String("path", c.Request.URL.Path))
+			countLimitRejection(LimitInFlight)
+			c.Header("Retry-After", overloadRetryAfter)
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Service Unavailable"})
+			return
+		}
+		defer func() { <-slots }()
+
+		c.Next()
+	}
+}
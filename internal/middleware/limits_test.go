@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// limitRejectionCount reads a counter through the admin endpoint.
+func limitRejectionCount(t *testing.T, reason string) int64 {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	LimitRejections(c)
+	var counts map[string]int64
+	if err := json.Unmarshal(w.Body.Bytes(), &counts); err != nil {
+		t.Fatal(err)
+	}
+	return counts[reason]
+}
+
+func TestMaxInFlightShedsExcess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const max = 3
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	r := gin.New()
+	// Como em cmd/main.go, o health check é registrado antes do limite e nunca é descartado
+	r.GET("/health/live", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.Use(MaxInFlight(max, zap.NewNop()))
+	r.GET("/slow", func(c *gin.Context) {
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	before := limitRejectionCount(t, LimitInFlight)
+
+	statuses := make(chan int, max)
+	for i := 0; i < max; i++ {
+		go func() {
+			resp, err := http.Get(server.URL + "/slow")
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+		<-entered
+	}
+
+	resp, err := http.Get(server.URL + "/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("request %d: status %d, want 503", max+1, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Retry-After"); got != "1" {
+		t.Fatalf("Retry-After %q, want 1", got)
+	}
+	if n := limitRejectionCount(t, LimitInFlight) - before; n != 1 {
+		t.Fatalf("%s counted %d times, want 1", LimitInFlight, n)
+	}
+
+	if resp, err := http.Get(server.URL + "/health/live"); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("health check while overloaded: %v %v, want 200", resp, err)
+	}
+
+	close(release)
+	for i := 0; i < max; i++ {
+		if status := <-statuses; status != http.StatusOK {
+			t.Fatalf("in-flight request: status %d, want 200", status)
+		}
+	}
+
+	// Com os slots liberados a próxima requisição passa
+	go func() { <-entered }()
+	resp, err = http.Get(server.URL + "/slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("request after drain: status %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestMaxInFlightDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(MaxInFlight(0, zap.NewNop()))
+	r.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", w.Code)
+	}
+}
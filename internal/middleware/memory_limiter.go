@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"sync"
+	"time"
+)
+
+// KeyLimiter rate-limits requests per key, such as the client IP.
+type KeyLimiter interface {
+	// Get returns the limiter of key, tracking the key from its first use.
+	Get(key string) Limiter
+	// Peek returns the limiter of key without tracking it; tracked is false for
+	// keys without recent requests, which have a full budget.
+	Peek(key string) (limiter Limiter, tracked bool)
+	// Cleanup forgets the keys without requests for longer than idle and
+	// returns how many were removed.
+	Cleanup(idle time.Duration) int
+	// Reset forgets every key and returns how many there were.
+	Reset() int
+}
+
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// MemoryLimiter is a KeyLimiter with one token bucket per key, kept in
+// memory. Keys only go away through Cleanup or Reset.
+type MemoryLimiter struct {
+	mu         sync.Mutex
+	visitors   map[string]*visitor
+	newLimiter func() *rate.Limiter
+}
+
+func NewMemoryLimiter(newLimiter func() *rate.Limiter) *MemoryLimiter {
+	return &MemoryLimiter{visitors: make(map[string]*visitor), newLimiter: newLimiter}
+}
+
+func (l *MemoryLimiter) Get(key string) Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	v, exists := l.visitors[key]
+	if !exists {
+		v = &visitor{limiter: l.newLimiter()}
+		l.visitors[key] = v
+	}
+	v.lastSeen = time.Now()
+	return tokenBucket{v.limiter}
+}
+
+func (l *MemoryLimiter) Peek(key string) (Limiter, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if v, exists := l.visitors[key]; exists {
+		return tokenBucket{v.limiter}, true
+	}
+	return tokenBucket{l.newLimiter()}, false
+}
+
+func (l *MemoryLimiter) Cleanup(idle time.Duration) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	removed := 0
+	cutoff := time.Now().Add(-idle)
+	for key, v := range l.visitors {
+		if v.lastSeen.Before(cutoff) {
+			delete(l.visitors, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+func (l *MemoryLimiter) Reset() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cleared := len(l.visitors)
+	l.visitors = make(map[string]*visitor)
+	return cleared
+}
+
+// CleanupVisitors periodically forgets the clients without requests for
+// longer than idle, so the per-IP limits don't grow with every address ever
+// seen. Their budget is full again by then. It returns when ctx is done.
+func (m *Middleware) CleanupVisitors(ctx context.Context, idle time.Duration) {
+	ticker := time.NewTicker(idle)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if removed := m.visitors.Cleanup(idle); removed > 0 {
+				m.logger.Debug("Removed idle rate-limit visitors", zap.Int("removed", removed))
+			}
+		}
+	}
+}
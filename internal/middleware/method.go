@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"net/http"
+	"strings"
+)
+
+// NormalizeMethod uppercases the request method before routing and, when
+// overrideHeader is set, lets POST requests carry the real method in that
+// header for clients behind proxies that only allow GET and POST. It wraps
+// the engine instead of being a gin middleware because gin picks the route
+// by method before running any middleware.
+func NormalizeMethod(overrideHeader string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Method = strings.ToUpper(r.Method)
+
+		if overrideHeader != "" && r.Method == http.MethodPost {
+			// Apenas POST pode ser sobrescrito, para que um GET não vire uma operação de escrita
+			if override := r.Header.Get(overrideHeader); override != "" {
+				if !config.IsValidMethod(override) {
+					http.Error(w, "Invalid method override", http.StatusBadRequest)
+					return
+				}
+				r.Method = strings.ToUpper(override)
+				r.Header.Del(overrideHeader)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newMethodGateway serves a route stored with lowercase methods behind
+// NormalizeMethod, registering the methods uppercased like cmd/main.go. The
+// backend answers with the method it received.
+func newMethodGateway(t *testing.T, overrideHeader string) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Override-Seen", r.Header.Get(overrideHeader))
+		w.Write([]byte(r.Method))
+	}))
+	t.Cleanup(backend.Close)
+
+	route := &config.Route{Path: "/items", ServiceURL: backend.URL, Methods: []string{"get", "post", "delete"}, IsActive: true}
+	h, _, _ := newTestProxy(t, newTestConfig(t), route)
+
+	r := gin.New()
+	for _, method := range route.Methods {
+		r.Handle(strings.ToUpper(method), route.Path, func(c *gin.Context) {
+			h.ServeHTTP(c.Writer, c.Request)
+		})
+	}
+
+	server := httptest.NewServer(NormalizeMethod(overrideHeader, r))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sendMethod(t *testing.T, server *httptest.Server, method string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+"/items", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestLowercaseMethods(t *testing.T) {
+	server := newMethodGateway(t, "")
+
+	for _, method := range []string{"get", "Post", "delete"} {
+		resp, body := sendMethod(t, server, method, nil)
+		if resp.StatusCode != http.StatusOK || body != strings.ToUpper(method) {
+			t.Fatalf("%s: got %d %q, want the backend to see %s", method, resp.StatusCode, body, strings.ToUpper(method))
+		}
+	}
+	if resp, _ := sendMethod(t, server, "put", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("put: status %d, want 404 from the router", resp.StatusCode)
+	}
+}
+
+func TestMethodOverrideHeader(t *testing.T) {
+	server := newMethodGateway(t, "X-HTTP-Method-Override")
+	override := func(value string) http.Header {
+		return http.Header{"X-Http-Method-Override": {value}}
+	}
+
+	resp, body := sendMethod(t, server, http.MethodPost, override("delete"))
+	if resp.StatusCode != http.StatusOK || body != http.MethodDelete {
+		t.Fatalf("POST overridden to delete: got %d %q, want DELETE", resp.StatusCode, body)
+	}
+	if seen := resp.Header.Get("X-Override-Seen"); seen != "" {
+		t.Fatalf("override header %q forwarded to the backend", seen)
+	}
+
+	// Só POST pode ser sobrescrito
+	if resp, body := sendMethod(t, server, http.MethodGet, override("DELETE")); body != http.MethodGet {
+		t.Fatalf("GET with override: got %d %q, want it served as GET", resp.StatusCode, body)
+	}
+
+	if resp, _ := sendMethod(t, server, http.MethodPost, override("FETCH")); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("invalid override: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestMethodOverrideDisabled(t *testing.T) {
+	server := newMethodGateway(t, "")
+
+	resp, body := sendMethod(t, server, http.MethodPost, http.Header{"X-Http-Method-Override": {"DELETE"}})
+	if resp.StatusCode != http.StatusOK || body != http.MethodPost {
+		t.Fatalf("got %d %q, want the override ignored", resp.StatusCode, body)
+	}
+}
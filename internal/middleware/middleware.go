@@ -1,11 +1,12 @@
 package middleware
 
 import (
+	"errors"
 	"github.com/diillson/api-gateway-go/internal/auth"
 	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/internal/routestore"
 	"github.com/diillson/api-gateway-go/pkg/config"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v4"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
 	"net/http"
@@ -17,40 +18,37 @@ import (
 type Middleware struct {
 	logger  *zap.Logger
 	limiter *rate.Limiter
-	routes  map[string]*config.Route
-	db      *database.Database
-}
+	// routes é o mesmo store recarregado pelo handler nas escritas do admin
+	routes *routestore.Store
+	db     *database.Database
+	cfg    *config.Config
 
-type visitor struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
-}
+	// visitors guarda o limite por IP de cada cliente
+	visitors KeyLimiter
 
-var visitors = make(map[string]*visitor)
-var mtx sync.Mutex
+	routeLimiters map[string]*routeLimit
+	routeMtx      sync.Mutex
 
-func NewMiddleware(logger *zap.Logger, routes map[string]*config.Route, db *database.Database) *Middleware {
-	return &Middleware{
-		logger:  logger,
-		limiter: rate.NewLimiter(1, 5),
-		routes:  routes,
-		db:      db,
-	}
+	traffic    map[string]*trafficWindow
+	trafficMtx sync.Mutex
 }
 
-func getVisitor(ip string) *rate.Limiter {
-	mtx.Lock()
-	defer mtx.Unlock()
-
-	v, exists := visitors[ip]
-	if !exists {
-		limiter := rate.NewLimiter(1, 15)
-		visitors[ip] = &visitor{limiter, time.Now()}
-		return limiter
+func NewMiddleware(logger *zap.Logger, routes *routestore.Store, db *database.Database, cfg *config.Config) *Middleware {
+	return &Middleware{
+		logger:        logger,
+		limiter:       rate.NewLimiter(1, 5),
+		routes:        routes,
+		db:            db,
+		cfg:           cfg,
+		visitors:      NewMemoryLimiter(newVisitorLimiter),
+		routeLimiters: make(map[string]*routeLimit),
+		traffic:       make(map[string]*trafficWindow),
 	}
+}
 
-	v.lastSeen = time.Now()
-	return v.limiter
+// newVisitorLimiter builds the per-IP limiter: 1 request per second, bursts of 15.
+func newVisitorLimiter() *rate.Limiter {
+	return rate.NewLimiter(1, 15)
 }
 
 func (m *Middleware) Authenticate(c *gin.Context) {
@@ -63,10 +61,82 @@ func (m *Middleware) Authenticate(c *gin.Context) {
 	c.Next()
 }
 
+// routeLimit is the limiter of a route together with the limit it was built
+// for, so a route update with a new limit replaces it.
+type routeLimit struct {
+	limiter Limiter
+	limit   int
+	period  time.Duration
+}
+
+// routeLimitFor returns the limit of route: its own RateLimit and
+// RateLimitPeriod, falling back to the defaults for the ones unset.
+func (m *Middleware) routeLimitFor(route *config.Route) (int, time.Duration) {
+	limit, period := m.cfg.RateLimit.DefaultLimit, m.cfg.RateLimit.DefaultPeriod
+	if route.RateLimit > 0 {
+		limit = route.RateLimit
+	}
+	if route.RateLimitPeriod > 0 {
+		period = route.RateLimitPeriod
+	}
+	return limit, period
+}
+
+// getRouteLimiter returns the shared limiter for a route, or nil when the
+// route has no limit.
+func (m *Middleware) getRouteLimiter(route *config.Route) Limiter {
+	limit, period := m.routeLimitFor(route)
+	if limit <= 0 || period <= 0 {
+		return nil
+	}
+
+	m.routeMtx.Lock()
+	defer m.routeMtx.Unlock()
+
+	current, exists := m.routeLimiters[route.Path]
+	if !exists || current.limit != limit || current.period != period {
+		current = &routeLimit{limiter: newRouteLimiter(m.cfg.RateLimit.Strategy, limit, period), limit: limit, period: period}
+		m.routeLimiters[route.Path] = current
+	}
+	return current.limiter
+}
+
 func (m *Middleware) RateLimit(c *gin.Context) {
-	limiter := getVisitor(c.ClientIP())
+	route := &config.Route{}
+	matched, routeMatched := GetMatchedRoute(c)
+	if routeMatched {
+		route = matched.Route
+	}
+
+	// Métodos fora da lista configurada não contam para nenhum limite
+	if !route.IsRateLimited(c.Request.Method, m.cfg.RateLimit.Methods) {
+		c.Next()
+		return
+	}
+
+	limiter := m.visitors.Get(c.ClientIP())
 	if !limiter.Allow() {
-		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too Many Requests"})
+		m.rejectRateLimited(c, limiter)
+		return
+	}
+
+	// Sem rota casada não há limite por rota: chavear pelo path enviado pelo cliente
+	// criaria um limitador para cada path desconhecido
+	if !routeMatched {
+		c.Next()
+		return
+	}
+
+	// Limite por rota: o configurado na rota ou o padrão, aplicado a todas as rotas do proxy
+	if routeLimiter := m.getRouteLimiter(route); routeLimiter != nil && !routeLimiter.Allow() {
+		limitType := "default"
+		if route.RateLimit > 0 {
+			limitType = "route"
+		}
+		m.logger.Warn("Rate limit exceeded",
+			zap.String("path", route.Path),
+			zap.String("limit_type", limitType))
+		m.rejectRateLimited(c, routeLimiter)
 		return
 	}
 
@@ -74,13 +144,13 @@ func (m *Middleware) RateLimit(c *gin.Context) {
 }
 
 func (m *Middleware) ValidateHeaders(c *gin.Context) {
-	route, exists := m.routes[c.Request.URL.Path]
+	matched, exists := GetMatchedRoute(c)
 	if !exists {
 		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Not Found"})
 		return
 	}
 
-	for _, header := range route.RequiredHeaders {
+	for _, header := range matched.Route.RequiredHeaders {
 		if c.GetHeader(header) == "" {
 			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Missing Headers"})
 			return
@@ -96,20 +166,31 @@ func (m *Middleware) Analytics(c *gin.Context) {
 	duration := time.Since(start)
 
 	path := c.Request.URL.Path
-	route, exists := m.routes[path]
-	if exists {
-		route.CallCount++
-		route.TotalResponse += duration
+	if matched, exists := GetMatchedRoute(c); exists {
+		route := matched.Route
+		path = route.Path
+		m.recordTraffic(path, c.Writer.Status())
+
+		callCount, totalResponse := m.routes.RecordCall(route, duration)
 
 		// Aqui atualizamos as métricas na base de dados
-		err := m.updateMetricsInDB(path, int(route.CallCount), route.TotalResponse)
+		err := m.updateMetricsInDB(path, int(callCount), totalResponse)
 		if err != nil {
 			m.logger.Error("Failed to update metrics in database", zap.Error(err))
 		}
 	}
 
+	// Size conta os bytes realmente escritos, então vale também para respostas chunked sem Content-Length
+	size := c.Writer.Size()
+	if size < 0 {
+		size = 0
+	}
+
 	m.logger.Info("Request processed",
+		zap.String("requestID", c.GetString(RequestIDKey)),
 		zap.String("path", path),
+		zap.Int("status", c.Writer.Status()),
+		zap.Int("responseSize", size),
 		zap.Duration("duration", duration))
 }
 
@@ -129,22 +210,24 @@ func (m *Middleware) updateMetricsInDB(path string, callCount int, totalResponse
 }
 
 func (m *Middleware) AuthenticateAdmin(c *gin.Context) {
+	// Sem autenticação, o admin só fica aberto se isso for liberado explicitamente
+	if !m.cfg.Auth.Enabled && m.cfg.Auth.AllowAnonymousAdmin {
+		c.Next()
+		return
+	}
+
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header not provided"})
 		return
 	}
 
-	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-	claims := &auth.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.NewValidationError("unexpected signing method", jwt.ValidationErrorSignatureInvalid)
-		}
-		return auth.JwtKey, nil
-	})
-
-	if err != nil || !token.Valid {
+	_, err := auth.ParseGatewayToken(strings.TrimPrefix(authHeader, "Bearer "))
+	if errors.Is(err, auth.ErrTokenRevoked) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+		return
+	}
+	if err != nil {
 		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 		return
 	}
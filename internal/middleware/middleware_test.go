@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/internal/handler"
+	"github.com/diillson/api-gateway-go/internal/routestore"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testGateway wires the proxy and the middlewares the way cmd/main.go does,
+// over a throwaway database, and serves them on a real listener.
+type testGateway struct {
+	server *httptest.Server
+	db     *database.Database
+	mw     *Middleware
+}
+
+// newTestProxy stores routes in a throwaway database and returns the proxy
+// and the middlewares reading them.
+func newTestProxy(t *testing.T, cfg *config.Config, routes ...*config.Route) (*handler.Handler, *Middleware, *database.Database) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	db, err := database.Open(filepath.Join(t.TempDir(), "routes.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range routes {
+		if err := db.AddRoute(route); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store := routestore.New(db, cfg.Routes.TrailingSlash)
+	if _, _, err := store.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	logger := zap.NewNop()
+	return handler.NewHandler(db, logger, cfg, store), NewMiddleware(logger, store, db, cfg), db
+}
+
+func newTestGateway(t *testing.T, cfg *config.Config, routes ...*config.Route) *testGateway {
+	t.Helper()
+	h, mw, db := newTestProxy(t, cfg, routes...)
+
+	r := gin.New()
+	for _, route := range routes {
+		for _, method := range route.Methods {
+			r.Handle(method, route.Path, mw.MatchRoute, mw.RateLimit, mw.Analytics, func(c *gin.Context) {
+				h.ServeHTTP(c.Writer, c.Request)
+			})
+		}
+	}
+	r.PUT("/admin/update", h.UpdateAPI)
+	r.GET("/admin/ratelimit/status", mw.RateLimitStatusAPI)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return &testGateway{server: server, db: db, mw: mw}
+}
+
+func (g *testGateway) do(t *testing.T, method, path string, body []byte) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, g.server.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+// update changes the route at path through the admin API.
+func (g *testGateway) update(t *testing.T, path string, change func(*config.Route)) {
+	t.Helper()
+	routes, err := g.db.GetRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, route := range routes {
+		if route.Path != path {
+			continue
+		}
+		change(route)
+		body, err := json.Marshal(route)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp := g.do(t, http.MethodPut, "/admin/update", body); resp.StatusCode != http.StatusOK {
+			t.Fatalf("update %s: status %d", path, resp.StatusCode)
+		}
+		return
+	}
+	t.Fatalf("route %s not found", path)
+}
+
+func (g *testGateway) expectStatuses(t *testing.T, method, path string, want ...int) {
+	t.Helper()
+	for i, status := range want {
+		if resp := g.do(t, method, path, nil); resp.StatusCode != status {
+			t.Fatalf("%s %s request %d: status %d, want %d", method, path, i+1, resp.StatusCode, status)
+		}
+	}
+}
+
+func newTestConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func newTestBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func TestRouteRateLimitUpdateTakesEffect(t *testing.T) {
+	backend := newTestBackend(t)
+	gw := newTestGateway(t, newTestConfig(t), &config.Route{
+		Path:            "/limited",
+		ServiceURL:      backend.URL,
+		Methods:         []string{http.MethodGet},
+		IsActive:        true,
+		RateLimit:       100,
+		RateLimitPeriod: time.Minute,
+	})
+
+	gw.expectStatuses(t, http.MethodGet, "/limited", http.StatusOK, http.StatusOK, http.StatusOK)
+
+	gw.update(t, "/limited", func(route *config.Route) { route.RateLimit = 2 })
+
+	gw.expectStatuses(t, http.MethodGet, "/limited", http.StatusOK, http.StatusOK, http.StatusTooManyRequests)
+}
+
+func TestRouteRateLimitMethodsUpdateTakesEffect(t *testing.T) {
+	backend := newTestBackend(t)
+	gw := newTestGateway(t, newTestConfig(t), &config.Route{
+		Path:             "/orders",
+		ServiceURL:       backend.URL,
+		Methods:          []string{http.MethodGet, http.MethodPost},
+		IsActive:         true,
+		RateLimit:        1,
+		RateLimitPeriod:  time.Minute,
+		RateLimitMethods: []string{http.MethodPost},
+	})
+
+	// Só POST conta para o limite
+	gw.expectStatuses(t, http.MethodGet, "/orders", http.StatusOK, http.StatusOK, http.StatusOK)
+
+	gw.update(t, "/orders", func(route *config.Route) { route.RateLimitMethods = []string{http.MethodGet} })
+
+	gw.expectStatuses(t, http.MethodGet, "/orders", http.StatusOK, http.StatusTooManyRequests)
+}
+
+func TestUnmatchedPathsShareNoRouteLimiter(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.RateLimit.DefaultLimit = 1
+	_, mw, _ := newTestProxy(t, cfg)
+
+	r := gin.New()
+	// Sem rota casada (ex.: NoRoute), como um path inventado pelo cliente
+	r.NoRoute(mw.MatchRoute, mw.RateLimit, func(c *gin.Context) { c.Status(http.StatusNotFound) })
+
+	// Poucas requisições para não chegar ao limite por IP
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/random-"+strconv.Itoa(i), nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("request %d: status %d, want 404", i+1, w.Code)
+		}
+	}
+
+	mw.routeMtx.Lock()
+	defer mw.routeMtx.Unlock()
+	if n := len(mw.routeLimiters); n != 0 {
+		t.Fatalf("%d route limiters created for unmatched paths, want none", n)
+	}
+}
+
+func TestAdminWriteSeenByProxyAndMiddlewares(t *testing.T) {
+	newNamedBackend := func(name string) *httptest.Server {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		t.Cleanup(backend.Close)
+		return backend
+	}
+	oldBackend, newBackend := newNamedBackend("old"), newNamedBackend("new")
+	gw := newTestGateway(t, newTestConfig(t), &config.Route{
+		Path:            "/items",
+		ServiceURL:      oldBackend.URL,
+		Methods:         []string{http.MethodGet},
+		IsActive:        true,
+		RateLimit:       100,
+		RateLimitPeriod: time.Minute,
+	})
+	get := func() (int, string) {
+		t.Helper()
+		resp, err := http.Get(gw.server.URL + "/items")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, string(body)
+	}
+
+	if status, body := get(); status != http.StatusOK || body != "old" {
+		t.Fatalf("before the update: %d %q, want 200 from the old backend", status, body)
+	}
+	stale, _ := gw.mw.routes.Get("/items")
+	gw.waitForCalls(t, stale, 1)
+
+	gw.update(t, "/items", func(route *config.Route) {
+		route.ServiceURL = newBackend.URL
+		route.RateLimit = 2
+	})
+	current, _ := gw.mw.routes.Get("/items")
+	if current == stale {
+		t.Fatal("the middlewares still see the route from before the update")
+	}
+	callsBefore, _ := gw.mw.routes.CallMetrics(current)
+
+	// O proxy usa o novo backend e o RateLimit o novo limite já na requisição seguinte
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		status, body := get()
+		if status != want {
+			t.Fatalf("request %d after the update: status %d, want %d", i+1, status, want)
+		}
+		if status == http.StatusOK && body != "new" {
+			t.Fatalf("request %d after the update served by %q, want the new backend", i+1, body)
+		}
+	}
+
+	// O Analytics conta na rota recarregada; a barrada pelo limite não chega a ele
+	gw.waitForCalls(t, current, callsBefore+2)
+	if staleCalls, _ := gw.mw.routes.CallMetrics(stale); staleCalls != 1 {
+		t.Fatalf("Analytics counted %d calls on the replaced route, want only the one before the update", staleCalls)
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryAfter estimates how long until limiter allows another request, without
+// consuming a token.
+func retryAfter(limiter *rate.Limiter) time.Duration {
+	reservation := limiter.Reserve()
+	defer reservation.Cancel()
+	if !reservation.OK() {
+		return 0
+	}
+	return reservation.Delay()
+}
+
+// rejectRateLimited aborts the request with the configured rate-limit status
+// and body, replacing {retry_after} with the seconds until the limiter allows
+// another request.
+func (m *Middleware) rejectRateLimited(c *gin.Context, limiter Limiter) {
+	seconds := strconv.Itoa(int(math.Ceil(limiter.RetryAfter().Seconds())))
+	body := strings.ReplaceAll(m.cfg.RateLimit.ResponseBody, "{retry_after}", seconds)
+
+	c.Header("Retry-After", seconds)
+	c.Data(m.cfg.RateLimit.ResponseStatus, m.cfg.RateLimit.ResponseContentType, []byte(body))
+	c.Abort()
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"io"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		configure   func(*config.RateLimitConfig)
+		status      int
+		contentType string
+		body        func(retryAfter string) string
+	}{
+		{
+			name:        "default",
+			configure:   func(*config.RateLimitConfig) {},
+			status:      http.StatusTooManyRequests,
+			contentType: "application/json; charset=utf-8",
+			body:        func(string) string { return `{"error":"Too Many Requests"}` },
+		},
+		{
+			name: "custom",
+			configure: func(cfg *config.RateLimitConfig) {
+				cfg.ResponseStatus = http.StatusServiceUnavailable
+				cfg.ResponseBody = `{"code":"SLOW_DOWN","retryInSeconds":{retry_after}}`
+				cfg.ResponseContentType = "application/problem+json"
+			},
+			status:      http.StatusServiceUnavailable,
+			contentType: "application/problem+json",
+			body: func(retryAfter string) string {
+				return `{"code":"SLOW_DOWN","retryInSeconds":` + retryAfter + `}`
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t)
+			tt.configure(&cfg.RateLimit)
+			gw := newTestGateway(t, cfg, &config.Route{
+				Path:            "/limited",
+				ServiceURL:      newTestBackend(t).URL,
+				Methods:         []string{http.MethodGet},
+				IsActive:        true,
+				RateLimit:       1,
+				RateLimitPeriod: time.Minute,
+			})
+
+			gw.expectStatuses(t, http.MethodGet, "/limited", http.StatusOK)
+
+			resp, err := http.Get(gw.server.URL + "/limited")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if got := resp.Header.Get("Content-Type"); got != tt.contentType {
+				t.Fatalf("Content-Type %q, want %q", got, tt.contentType)
+			}
+			// Um token por minuto: a próxima requisição é permitida em até 60s
+			retryAfter := resp.Header.Get("Retry-After")
+			if seconds, err := strconv.Atoi(retryAfter); err != nil || seconds < 1 || seconds > 60 {
+				t.Fatalf("Retry-After %q, want 1-60 seconds", retryAfter)
+			}
+			if want := tt.body(retryAfter); string(body) != want {
+				t.Fatalf("body %q, want %q", body, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+	"math"
+	"net/http"
+	"time"
+)
+
+// RateLimitStatus is the current state of one rate-limit key.
+type RateLimitStatus struct {
+	Key  string `json:"key"`
+	Type string `json:"type"`
+	// Tracked é false quando a chave ainda não fez requisições (orçamento cheio)
+	Tracked       bool          `json:"tracked"`
+	Enabled       bool          `json:"enabled"`
+	Limit         int           `json:"limit"`
+	RatePerSecond float64       `json:"ratePerSecond"`
+	Remaining     int           `json:"remaining"`
+	ResetIn       time.Duration `json:"resetIn"`
+}
+
+// limiterStatus reads a limiter without consuming tokens. ResetIn is how long
+// until the budget is full again.
+func limiterStatus(limiter *rate.Limiter, now time.Time) (remaining int, resetIn time.Duration) {
+	tokens := math.Max(limiter.TokensAt(now), 0)
+	burst := float64(limiter.Burst())
+	if limit := float64(limiter.Limit()); limit > 0 && tokens < burst {
+		resetIn = time.Duration((burst - tokens) / limit * float64(time.Second))
+	}
+	return int(tokens), resetIn
+}
+
+// RateLimitStatusAPI reports how close a client IP (type=ip) or a route
+// (type=api, key is the route path) is to its limit, without counting as a
+// request.
+func (m *Middleware) RateLimitStatusAPI(c *gin.Context) {
+	key, kind := c.Query("key"), c.DefaultQuery("type", "ip")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Key query parameter required"})
+		return
+	}
+
+	var (
+		limiter Limiter
+		tracked bool
+	)
+	switch kind {
+	case "ip":
+		limiter, tracked = m.visitors.Peek(key)
+	case "api":
+		m.routeMtx.Lock()
+		current, ok := m.routeLimiters[key]
+		m.routeMtx.Unlock()
+		if ok {
+			limiter, tracked = current.limiter, true
+		} else {
+			route, exists := m.routes.Lookup(key)
+			if !exists {
+				route = &config.Route{}
+			}
+			if limit, period := m.routeLimitFor(route); limit > 0 && period > 0 {
+				limiter = newRouteLimiter(m.cfg.RateLimit.Strategy, limit, period)
+			}
+		}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type must be ip or api"})
+		return
+	}
+
+	status := RateLimitStatus{Key: key, Type: kind, Tracked: tracked}
+	if limiter != nil {
+		status.Enabled = true
+		status.Limit, status.Remaining, status.ResetIn = limiter.Status(time.Now())
+		switch l := limiter.(type) {
+		case tokenBucket:
+			status.RatePerSecond = float64(l.Limit())
+		case *slidingWindowLimiter:
+			status.RatePerSecond = float64(l.limit) / l.period.Seconds()
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
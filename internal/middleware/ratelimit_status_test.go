@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func (g *testGateway) rateLimitStatus(t *testing.T, kind, key string) (int, RateLimitStatus) {
+	t.Helper()
+	query := url.Values{"type": {kind}, "key": {key}}
+	resp, err := http.Get(g.server.URL + "/admin/ratelimit/status?" + query.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var status RateLimitStatus
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, status
+}
+
+func TestRateLimitStatusAPI(t *testing.T) {
+	gw := newTestGateway(t, newTestConfig(t), &config.Route{
+		Path:            "/limited",
+		ServiceURL:      newTestBackend(t).URL,
+		Methods:         []string{http.MethodGet},
+		IsActive:        true,
+		RateLimit:       3,
+		RateLimitPeriod: time.Minute,
+	})
+
+	_, status := gw.rateLimitStatus(t, "api", "/limited")
+	if status.Tracked || !status.Enabled || status.Limit != 3 || status.Remaining != 3 {
+		t.Fatalf("before any request: %+v, want an untracked full budget of 3", status)
+	}
+
+	gw.expectStatuses(t, http.MethodGet, "/limited", http.StatusOK, http.StatusOK)
+
+	// Consultar o status não consome o orçamento
+	for i := 0; i < 5; i++ {
+		_, status = gw.rateLimitStatus(t, "api", "/limited")
+		if !status.Tracked || status.Remaining != 1 || status.ResetIn <= 0 {
+			t.Fatalf("after 2 requests, query %d: %+v, want 1 remaining", i+1, status)
+		}
+	}
+	gw.expectStatuses(t, http.MethodGet, "/limited", http.StatusOK, http.StatusTooManyRequests)
+
+	if _, status = gw.rateLimitStatus(t, "api", "/limited"); status.Remaining != 0 {
+		t.Fatalf("after the limit: %+v, want 0 remaining", status)
+	}
+
+	if _, status = gw.rateLimitStatus(t, "ip", "127.0.0.1"); !status.Tracked || !status.Enabled {
+		t.Fatalf("client IP: %+v, want it tracked", status)
+	}
+	if _, status = gw.rateLimitStatus(t, "ip", "203.0.113.9"); status.Tracked {
+		t.Fatalf("unknown IP: %+v, want it untracked", status)
+	}
+}
+
+func TestRateLimitStatusAPIInvalidQuery(t *testing.T) {
+	gw := newTestGateway(t, newTestConfig(t))
+
+	for _, query := range [][2]string{{"ip", ""}, {"user", "alice"}} {
+		if code, _ := gw.rateLimitStatus(t, query[0], query[1]); code != http.StatusBadRequest {
+			t.Fatalf("type=%s key=%q: status %d, want 400", query[0], query[1], code)
+		}
+	}
+}
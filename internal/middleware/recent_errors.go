@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type RequestError struct {
+	Path      string    `json:"path"`
+	Method    string    `json:"method"`
+	Status    int       `json:"status"`
+	ErrorType string    `json:"errorType"`
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"requestID,omitempty"`
+}
+
+// ErrorRecorder keeps the last N failed requests in a fixed-size ring buffer.
+type ErrorRecorder struct {
+	mu      sync.Mutex
+	entries []RequestError
+	next    int
+	full    bool
+}
+
+func NewErrorRecorder(size int) *ErrorRecorder {
+	if size <= 0 {
+		size = 100
+	}
+	return &ErrorRecorder{entries: make([]RequestError, size)}
+}
+
+func (e *ErrorRecorder) Record(c *gin.Context) {
+	c.Next()
+
+	status := c.Writer.Status()
+	if status < http.StatusBadRequest {
+		return
+	}
+
+	errorType := http.StatusText(status)
+	if len(c.Errors) > 0 {
+		errorType = c.Errors.Last().Error()
+	}
+
+	e.add(RequestError{
+		Path:      c.Request.URL.Path,
+		Method:    c.Request.Method,
+		Status:    status,
+		ErrorType: errorType,
+		Timestamp: time.Now(),
+		RequestID: c.GetString(RequestIDKey),
+	})
+}
+
+func (e *ErrorRecorder) add(entry RequestError) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.entries[e.next] = entry
+	e.next = (e.next + 1) % len(e.entries)
+	if e.next == 0 {
+		e.full = true
+	}
+}
+
+// Recent returns the recorded errors, newest first.
+func (e *ErrorRecorder) Recent() []RequestError {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	count := e.next
+	if e.full {
+		count = len(e.entries)
+	}
+
+	recent := make([]RequestError, 0, count)
+	for i := 1; i <= count; i++ {
+		idx := (e.next - i + len(e.entries)) % len(e.entries)
+		recent = append(recent, e.entries[idx])
+	}
+	return recent
+}
+
+func (e *ErrorRecorder) RecentErrors(c *gin.Context) {
+	c.JSON(http.StatusOK, e.Recent())
+}
@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ReconcileRoutes periodically reloads the route store shared with the proxy
+// from the database, logging every divergence it corrects. It is how changes
+// made by other writers of the database reach this gateway and returns when
+// ctx is done.
+func (m *Middleware) ReconcileRoutes(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.reconcileRoutes()
+		}
+	}
+}
+
+func (m *Middleware) reconcileRoutes() error {
+	cached, fresh, err := m.routes.Reload()
+	if err != nil {
+		m.logger.Error("Route reconciliation failed to load routes", zap.Error(err))
+		return err
+	}
+
+	corrections := 0
+	for path, route := range fresh {
+		previous, exists := cached[path]
+		switch {
+		case !exists:
+			m.logger.Warn("Route reconciliation added missing route", zap.String("path", path))
+		case previous.Version != route.Version:
+			m.logger.Warn("Route reconciliation refreshed stale route",
+				zap.String("path", path),
+				zap.Int64("cachedVersion", previous.Version),
+				zap.Int64("version", route.Version))
+		default:
+			continue
+		}
+		corrections++
+	}
+	for path := range cached {
+		if _, exists := fresh[path]; !exists {
+			m.logger.Warn("Route reconciliation removed deleted route", zap.String("path", path))
+			corrections++
+		}
+	}
+
+	if corrections > 0 {
+		m.logger.Info("Route reconciliation finished", zap.Int("corrections", corrections))
+	}
+	return nil
+}
+
+// Reset reloads the shared routes from the database and, if clearRateLimits
+// is set, drops every rate-limit counter so all clients start with a full
+// budget. It returns how many rate-limit keys were cleared.
+func (m *Middleware) Reset(clearRateLimits bool) (int, error) {
+	if err := m.reconcileRoutes(); err != nil {
+		return 0, err
+	}
+	if !clearRateLimits {
+		return 0, nil
+	}
+
+	cleared := m.visitors.Reset()
+
+	m.routeMtx.Lock()
+	cleared += len(m.routeLimiters)
+	m.routeLimiters = make(map[string]*routeLimit)
+	m.routeMtx.Unlock()
+
+	return cleared, nil
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDKey is the gin context key holding the resolved request ID.
+const RequestIDKey = "requestID"
+
+const maxRequestIDLength = 128
+
+// RequestID resolves the request ID from the first inbound header present, or
+// generates one, and propagates it under the canonical outbound header both to
+// the backend and in the response. The other inbound names are removed so
+// backends see a single ID.
+func RequestID(inbound []string, outbound string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := ""
+		for _, name := range inbound {
+			if value := c.GetHeader(name); value != "" && validRequestID(value) {
+				id = value
+				break
+			}
+		}
+		if id == "" {
+			id = newRequestID()
+		}
+
+		for _, name := range inbound {
+			c.Request.Header.Del(name)
+		}
+		c.Request.Header.Set(outbound, id)
+		c.Header(outbound, id)
+		c.Set(RequestIDKey, id)
+
+		c.Next()
+	}
+}
+
+// validRequestID rejects IDs that are too long or carry non-printable
+// characters, so a client can't inject arbitrary content into logs.
+func validRequestID(id string) bool {
+	if len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
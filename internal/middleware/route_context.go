@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// MatchedRouteKey is the gin context key holding the *MatchedRoute of the
+// request.
+const MatchedRouteKey = "matchedRoute"
+
+// MatchedRoute is the route resolved for a request together with the path
+// parameters captured by the router.
+type MatchedRoute struct {
+	Route  *config.Route
+	Params gin.Params
+}
+
+// MatchRoute resolves the route once and stores it in the context, so the
+// middlewares after it don't repeat the lookup. Requests for paths that are
+// no longer configured continue without a matched route.
+func (m *Middleware) MatchRoute(c *gin.Context) {
+	// O padrão registrado no gin (ex.: /users/:id) é a chave da rota; o path da URL só serve para rotas estáticas
+	path := c.FullPath()
+	if path == "" {
+		path = c.Request.URL.Path
+	}
+	if route, exists := m.routes.Lookup(path); exists {
+		c.Set(MatchedRouteKey, &MatchedRoute{Route: route, Params: c.Params})
+	}
+	c.Next()
+}
+
+// GetMatchedRoute returns the route stored by MatchRoute.
+func GetMatchedRoute(c *gin.Context) (*MatchedRoute, bool) {
+	value, exists := c.Get(MatchedRouteKey)
+	if !exists {
+		return nil, false
+	}
+	matched, ok := value.(*MatchedRoute)
+	return matched, ok
+}
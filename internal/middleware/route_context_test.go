@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchRouteStoresRoute(t *testing.T) {
+	_, mw, _ := newTestProxy(t, newTestConfig(t),
+		&config.Route{Path: "/users/:id", ServiceURL: "http://users.internal", Methods: []string{http.MethodGet}, IsActive: true},
+		&config.Route{Path: "/health", ServiceURL: "http://health.internal", Methods: []string{http.MethodGet}, IsActive: true},
+	)
+
+	var (
+		matched *MatchedRoute
+		found   bool
+	)
+	capture := func(c *gin.Context) {
+		matched, found = GetMatchedRoute(c)
+	}
+	r := gin.New()
+	r.GET("/users/:id", mw.MatchRoute, capture)
+	r.GET("/health", mw.MatchRoute, capture)
+	// Registrada no gin, mas removida do banco
+	r.GET("/removed", mw.MatchRoute, capture)
+
+	serve := func(path string) {
+		t.Helper()
+		matched, found = nil, false
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	serve("/users/42")
+	if !found || matched.Route.Path != "/users/:id" || matched.Route.ServiceURL != "http://users.internal" {
+		t.Fatalf("/users/42: matched %+v, found %v; want the /users/:id route", matched, found)
+	}
+	if id := matched.Params.ByName("id"); id != "42" {
+		t.Fatalf("/users/42: param id %q, want 42", id)
+	}
+
+	serve("/health")
+	if !found || matched.Route.Path != "/health" || len(matched.Params) != 0 {
+		t.Fatalf("/health: matched %+v, found %v; want the static route without params", matched, found)
+	}
+
+	serve("/removed")
+	if found {
+		t.Fatalf("/removed: matched %+v, want no route", matched)
+	}
+}
+
+func TestGetMatchedRouteWithoutMatch(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if matched, found := GetMatchedRoute(c); found || matched != nil {
+		t.Fatalf("got %+v, %v; want nothing before MatchRoute runs", matched, found)
+	}
+
+	c.Set(MatchedRouteKey, "not a route")
+	if _, found := GetMatchedRoute(c); found {
+		t.Fatal("value of the wrong type reported as a matched route")
+	}
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"golang.org/x/time/rate"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter is a rate limit: the one shared by every request to a route, or
+// the one of a key in a KeyLimiter.
+type Limiter interface {
+	Allow() bool
+	// RetryAfter estimates how long until a request would be allowed, without consuming budget.
+	RetryAfter() time.Duration
+	// Status reports the limit and the budget left at now, without consuming it.
+	Status(now time.Time) (limit, remaining int, resetIn time.Duration)
+}
+
+// newRouteLimiter builds a limiter of limit requests per period with the
+// configured strategy.
+func newRouteLimiter(strategy string, limit int, period time.Duration) Limiter {
+	if strategy == config.RateLimitStrategySlidingWindow {
+		return newSlidingWindowLimiter(limit, period)
+	}
+	return tokenBucket{rate.NewLimiter(rate.Every(period/time.Duration(limit)), limit)}
+}
+
+// tokenBucket refills one token every period/limit and allows bursts of up to
+// limit, so up to twice the limit can pass within one period.
+type tokenBucket struct {
+	*rate.Limiter
+}
+
+func (b tokenBucket) RetryAfter() time.Duration {
+	return retryAfter(b.Limiter)
+}
+
+func (b tokenBucket) Status(now time.Time) (int, int, time.Duration) {
+	remaining, resetIn := limiterStatus(b.Limiter, now)
+	return b.Burst(), remaining, resetIn
+}
+
+// slidingWindowLimiter approximates a sliding window with the counts of the
+// current and the previous fixed windows, weighting the previous one by how
+// much of it still overlaps the sliding window. It never lets more than limit
+// requests through in any period and keeps only two counters per route.
+type slidingWindowLimiter struct {
+	mu          sync.Mutex
+	limit       int
+	period      time.Duration
+	windowStart time.Time
+	prev, curr  int
+}
+
+func newSlidingWindowLimiter(limit int, period time.Duration) *slidingWindowLimiter {
+	return &slidingWindowLimiter{limit: limit, period: period, windowStart: time.Now()}
+}
+
+// advance moves the fixed windows forward to the one containing now.
+func (l *slidingWindowLimiter) advance(now time.Time) {
+	elapsed := int64(now.Sub(l.windowStart) / l.period)
+	if elapsed <= 0 {
+		return
+	}
+	if elapsed == 1 {
+		l.prev = l.curr
+	} else {
+		l.prev = 0
+	}
+	l.curr = 0
+	l.windowStart = l.windowStart.Add(time.Duration(elapsed) * l.period)
+}
+
+// estimate is the number of requests counted in the sliding window ending at now.
+func (l *slidingWindowLimiter) estimate(now time.Time) float64 {
+	overlap := 1 - float64(now.Sub(l.windowStart))/float64(l.period)
+	return float64(l.prev)*overlap + float64(l.curr)
+}
+
+func (l *slidingWindowLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.advance(now)
+	if l.estimate(now)+1 > float64(l.limit) {
+		return false
+	}
+	l.curr++
+	return true
+}
+
+func (l *slidingWindowLimiter) RetryAfter() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.advance(now)
+	if l.estimate(now)+1 <= float64(l.limit) {
+		return 0
+	}
+
+	// Ainda nesta janela, se o peso decrescente da anterior bastar
+	windowEnd := l.windowStart.Add(l.period)
+	if free := float64(l.limit - l.curr - 1); free >= 0 && l.prev > 0 {
+		at := l.windowStart.Add(time.Duration((1 - free/float64(l.prev)) * float64(l.period)))
+		return at.Sub(now)
+	}
+
+	// Senão, na próxima janela, quando a atual vira a anterior e perde peso
+	if l.curr == 0 || l.limit < 1 {
+		return windowEnd.Sub(now)
+	}
+	wait := math.Max(1-float64(l.limit-1)/float64(l.curr), 0)
+	return windowEnd.Sub(now) + time.Duration(wait*float64(l.period))
+}
+
+func (l *slidingWindowLimiter) Status(now time.Time) (int, int, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.advance(now)
+	remaining := int(math.Max(math.Floor(float64(l.limit)-l.estimate(now)), 0))
+
+	// O orçamento fica cheio quando nenhuma janela com requisições sobrepõe mais a janela deslizante
+	var resetIn time.Duration
+	switch {
+	case l.curr > 0:
+		resetIn = l.windowStart.Add(2 * l.period).Sub(now)
+	case l.prev > 0:
+		resetIn = l.windowStart.Add(l.period).Sub(now)
+	}
+	return l.limit, remaining, resetIn
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"testing"
+	"time"
+)
+
+// allowed calls Allow n times and counts the requests let through.
+func allowed(limiter Limiter, n int) int {
+	count := 0
+	for i := 0; i < n; i++ {
+		if limiter.Allow() {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSlidingWindowRejectsTokenBucketBurst(t *testing.T) {
+	const limit = 10
+	const period = time.Second
+
+	tests := []struct {
+		strategy string
+		// Faixa do total aceito nas duas rajadas, ambas dentro do mesmo período
+		minAllowed, maxAllowed int
+	}{
+		// O balde começa cheio e reabastece durante o período: passa bem mais que o limite
+		{config.RateLimitStrategyTokenBucket, limit + 5, 2 * limit},
+		{config.RateLimitStrategySlidingWindow, limit, limit},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.strategy, func(t *testing.T) {
+			t.Parallel()
+			limiter := newRouteLimiter(tt.strategy, limit, period)
+			start := time.Now()
+
+			first := allowed(limiter, limit)
+			time.Sleep(period * 6 / 10)
+			second := allowed(limiter, limit)
+
+			if elapsed := time.Since(start); elapsed >= period {
+				t.Skipf("bursts took %v, more than one period", elapsed)
+			}
+			if first != limit {
+				t.Fatalf("first burst: %d of %d allowed, want all", first, limit)
+			}
+			if total := first + second; total < tt.minAllowed || total > tt.maxAllowed {
+				t.Fatalf("%d requests allowed within one period with a limit of %d, want %d to %d", total, limit, tt.minAllowed, tt.maxAllowed)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SecurityHeaders adds the standard hardening headers to every response.
+func SecurityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("X-Frame-Options", "DENY")
+		h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+		if c.Request.TLS != nil {
+			h.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		}
+		c.Next()
+	}
+}
+
+// ResponseHeaders adds the configured headers to every response, proxied or
+// generated by the gateway. {hostname} in a value is replaced by the instance
+// hostname once, when the middleware is built.
+func ResponseHeaders(headers map[string]string) gin.HandlerFunc {
+	hostname, _ := os.Hostname()
+	expanded := make(map[string]string, len(headers))
+	for name, value := range headers {
+		expanded[http.CanonicalHeaderKey(name)] = strings.ReplaceAll(value, "{hostname}", hostname)
+	}
+
+	return func(c *gin.Context) {
+		h := c.Writer.Header()
+		for name, value := range expanded {
+			h.Set(name, value)
+		}
+		c.Next()
+	}
+}
+
+// CORS answers preflight requests and sets the CORS headers for allowed
+// origins. It must run before authentication since preflights carry no token.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	allowAll := false
+	allowed := make(map[string]bool)
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		allowed[origin] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		if !allowAll && !allowed[origin] {
+			c.Next()
+			return
+		}
+
+		if allowAll {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		// Preflight: responde direto sem passar pela autenticação
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", methods)
+			h.Set("Access-Control-Allow-Headers", headers)
+			h.Set("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
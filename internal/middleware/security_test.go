@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSecurityEngine applies the security middlewares the way cmd/main.go
+// does, in front of a route that must not be reached by preflights.
+func newSecurityEngine(cors config.CORSConfig, reached *int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(SecurityHeaders())
+	r.Use(CORS(cors))
+	// Simula a autenticação: tudo que passar do CORS sem token é barrado
+	r.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+		}
+	})
+	handle := func(c *gin.Context) {
+		*reached++
+		c.Status(http.StatusOK)
+	}
+	r.GET("/api/users", handle)
+	r.OPTIONS("/api/users", handle)
+	return r
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	var reached int
+	r := newSecurityEngine(config.CORSConfig{}, &reached)
+
+	for _, authorization := range []string{"Bearer token", ""} {
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		// Os headers valem também para respostas de erro
+		for name, want := range map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"X-Frame-Options":        "DENY",
+			"Referrer-Policy":        "strict-origin-when-cross-origin",
+		} {
+			if got := w.Header().Get(name); got != want {
+				t.Fatalf("status %d: %s %q, want %q", w.Code, name, got, want)
+			}
+		}
+		if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+			t.Fatalf("HSTS %q sent over plain HTTP", got)
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	var reached int
+	r := newSecurityEngine(config.CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		MaxAge:         time.Hour,
+	}, &reached)
+
+	preflight := func(origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, "/api/users", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// O preflight não carrega token e é respondido antes da autenticação
+	w := preflight("https://app.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status %d, want 204", w.Code)
+	}
+	for name, want := range map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Authorization, Content-Type",
+		"Access-Control-Max-Age":       "3600",
+		"Vary":                         "Origin",
+	} {
+		if got := w.Header().Get(name); got != want {
+			t.Fatalf("preflight %s %q, want %q", name, got, want)
+		}
+	}
+	if reached != 0 {
+		t.Fatalf("preflight reached the route handler")
+	}
+
+	w = preflight("https://evil.example.com")
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("preflight from disallowed origin: status %d, want it to fall through to auth (401)", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("disallowed origin got Access-Control-Allow-Origin %q", got)
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	var reached int
+	r := newSecurityEngine(config.CORSConfig{AllowedOrigins: []string{"*"}}, &reached)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Authorization", "Bearer token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || reached != 1 {
+		t.Fatalf("status %d, handler reached %d times; want the request served", w.Code, reached)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Fatalf("Access-Control-Allow-Origin %q, want *", got)
+	}
+}
+
+func TestResponseHeadersOnProxiedAndErrorResponses(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("from backend"))
+	}))
+	defer backend.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	failing.Close()
+
+	h, _, _ := newTestProxy(t, newTestConfig(t),
+		&config.Route{Path: "/users", ServiceURL: backend.URL, Methods: []string{http.MethodGet}, IsActive: true},
+		&config.Route{Path: "/down", ServiceURL: failing.URL, Methods: []string{http.MethodGet}, IsActive: true},
+	)
+	proxy := func(c *gin.Context) { h.ServeHTTP(c.Writer, c.Request) }
+
+	// Mesma ordem de cmd/main.go: os headers globais vêm antes dos limites
+	r := gin.New()
+	r.Use(ResponseHeaders(map[string]string{
+		"x-gateway-version": "1.4.0",
+		"X-Served-By":       "gw-{hostname}",
+	}))
+	r.Use(MaxPathLength(16, zap.NewNop()))
+	r.GET("/users", proxy)
+	r.GET("/down", proxy)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/users", http.StatusOK},
+		{"/down", http.StatusBadGateway},
+		{"/users/" + strings.Repeat("x", 32), http.StatusRequestURITooLong},
+		{"/missing", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.status {
+			t.Fatalf("%s: status %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		if got := resp.Header.Get("X-Gateway-Version"); got != "1.4.0" {
+			t.Fatalf("%s: X-Gateway-Version %q, want 1.4.0", tt.path, got)
+		}
+		if got, want := resp.Header.Get("X-Served-By"), "gw-"+hostname; got != want {
+			t.Fatalf("%s: X-Served-By %q, want %q", tt.path, got, want)
+		}
+	}
+}
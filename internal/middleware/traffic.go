@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const trafficWindowSeconds = 60
+
+type trafficBucket struct {
+	second   int64
+	requests int
+	errors   int
+}
+
+type TrafficPoint struct {
+	Time     time.Time `json:"time"`
+	Requests int       `json:"requests"`
+	Errors   int       `json:"errors"`
+}
+
+// trafficWindow counts requests in one-second buckets over the last minute.
+type trafficWindow struct {
+	mu      sync.Mutex
+	buckets [trafficWindowSeconds]trafficBucket
+}
+
+func (w *trafficWindow) record(now time.Time, isError bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	second := now.Unix()
+	b := &w.buckets[second%trafficWindowSeconds]
+	if b.second != second {
+		*b = trafficBucket{second: second}
+	}
+	b.requests++
+	if isError {
+		b.errors++
+	}
+}
+
+func (w *trafficWindow) snapshot(now time.Time) []TrafficPoint {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	points := make([]TrafficPoint, 0, trafficWindowSeconds)
+	current := now.Unix()
+	for second := current - trafficWindowSeconds + 1; second <= current; second++ {
+		point := TrafficPoint{Time: time.Unix(second, 0).UTC()}
+		if b := w.buckets[second%trafficWindowSeconds]; b.second == second {
+			point.Requests = b.requests
+			point.Errors = b.errors
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+func (m *Middleware) recordTraffic(path string, status int) {
+	m.trafficMtx.Lock()
+	window, exists := m.traffic[path]
+	if !exists {
+		window = &trafficWindow{}
+		m.traffic[path] = window
+	}
+	m.trafficMtx.Unlock()
+
+	window.record(time.Now(), status >= http.StatusInternalServerError)
+}
+
+// RouteTraffic returns the per-second request and error counts of a route
+// over the last minute.
+func (m *Middleware) RouteTraffic(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Path query parameter required"})
+		return
+	}
+
+	m.trafficMtx.Lock()
+	window, exists := m.traffic[path]
+	m.trafficMtx.Unlock()
+
+	if !exists {
+		window = &trafficWindow{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"path": path, "points": window.snapshot(time.Now())})
+}
@@ -0,0 +1,100 @@
+package routestore
+
+import (
+	"github.com/diillson/api-gateway-go/internal/database"
+	"github.com/diillson/api-gateway-go/pkg/config"
+	"sync"
+	"time"
+)
+
+// Store is the in-memory copy of the routes in the database. The proxy handler
+// and the middlewares share one Store, so a reload made by either side (admin
+// writes, reconciliation, reset) is seen by both at once.
+type Store struct {
+	db            *database.Database
+	trailingSlash string
+
+	// mu protege routes, substituído a cada recarga do banco
+	mu     sync.RWMutex
+	routes map[string]*config.Route
+
+	// metricsMu protege CallCount e TotalResponse das rotas, alterados a cada requisição
+	metricsMu sync.Mutex
+}
+
+// New returns an empty store; call Reload to fill it from db.
+func New(db *database.Database, trailingSlash string) *Store {
+	return &Store{db: db, trailingSlash: trailingSlash, routes: make(map[string]*config.Route)}
+}
+
+// Reload replaces the routes with the ones in the database, returning the
+// routes it replaced and the new ones.
+func (s *Store) Reload() (previous, current map[string]*config.Route, err error) {
+	routes, err := s.db.GetRoutes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	current = make(map[string]*config.Route, len(routes))
+	for _, route := range routes {
+		current[route.Path] = route
+	}
+
+	s.mu.Lock()
+	previous, s.routes = s.routes, current
+	s.mu.Unlock()
+	return previous, current, nil
+}
+
+// Lookup finds the route for a request path, accepting the path with or
+// without trailing slash when the policy is "ignore".
+func (s *Store) Lookup(path string) (*config.Route, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	route, exists := s.routes[path]
+	if !exists && s.trailingSlash == config.TrailingSlashIgnore {
+		route, exists = s.routes[config.ToggleTrailingSlash(path)]
+	}
+	return route, exists
+}
+
+// Get returns the route registered exactly at path.
+func (s *Store) Get(path string) (*config.Route, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	route, exists := s.routes[path]
+	return route, exists
+}
+
+// All returns every route, in no particular order.
+func (s *Store) All() []*config.Route {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routes := make([]*config.Route, 0, len(s.routes))
+	for _, route := range s.routes {
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// RecordCall adds a call that took duration to the route's metrics and returns
+// the new totals. Routes are shared by concurrent requests, so the counters
+// are only changed under the store's lock.
+func (s *Store) RecordCall(route *config.Route, duration time.Duration) (callCount int64, totalResponse time.Duration) {
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+
+	route.CallCount++
+	route.TotalResponse += duration
+	return route.CallCount, route.TotalResponse
+}
+
+// CallMetrics returns the route's call count and total response time.
+func (s *Store) CallMetrics(route *config.Route) (callCount int64, totalResponse time.Duration) {
+	s.metricsMu.Lock()
+	defer s.metricsMu.Unlock()
+	return route.CallCount, route.TotalResponse
+}
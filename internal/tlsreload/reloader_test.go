@@ -0,0 +1,157 @@
+package tlsreload
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"go.uber.org/zap"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed pair for commonName, with the given
+// modification time so rotations are detected regardless of clock
+// resolution.
+func writeCertificate(t *testing.T, certFile, keyFile, commonName string, modTime time.Time) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for file, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: der},
+		keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// serveTLS serves HTTPS with the reloader's certificates and returns a
+// function reporting the common name presented on a new connection.
+func serveTLS(t *testing.T, reloader *Reloader) func() string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate},
+	}
+	go server.ServeTLS(ln, "", "")
+	t.Cleanup(func() { server.Close() })
+
+	return func() string {
+		t.Helper()
+		// Sem keep-alive cada requisição faz um novo handshake
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		}}
+		resp, err := client.Get("https://" + ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+}
+
+func TestReloaderPicksUpRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Minute)
+	writeCertificate(t, certFile, keyFile, "gateway-v1", start)
+
+	reloader, err := NewReloader(certFile, keyFile, 10*time.Millisecond, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := serveTLS(t, reloader)
+
+	if cn := served(); cn != "gateway-v1" {
+		t.Fatalf("initial certificate %q, want gateway-v1", cn)
+	}
+
+	writeCertificate(t, certFile, keyFile, "gateway-v2", start.Add(time.Second))
+	time.Sleep(20 * time.Millisecond)
+	if cn := served(); cn != "gateway-v2" {
+		t.Fatalf("after rotation: certificate %q, want gateway-v2", cn)
+	}
+
+	// Um par inválido (rotação pela metade) mantém o certificado atual
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(keyFile, start.Add(2*time.Second), start.Add(2*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if cn := served(); cn != "gateway-v2" {
+		t.Fatalf("after invalid rotation: certificate %q, want gateway-v2 kept", cn)
+	}
+}
+
+func TestReloaderIntervalDisabled(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Minute)
+	writeCertificate(t, certFile, keyFile, "gateway-v1", start)
+
+	reloader, err := NewReloader(certFile, keyFile, 0, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := serveTLS(t, reloader)
+
+	writeCertificate(t, certFile, keyFile, "gateway-v2", start.Add(time.Second))
+	if cn := served(); cn != "gateway-v1" {
+		t.Fatalf("certificate %q, want gateway-v1 with reloading disabled", cn)
+	}
+}
+
+func TestNewReloaderInvalidPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if _, err := NewReloader(certFile, keyFile, time.Second, zap.NewNop()); err == nil {
+		t.Fatal("missing files accepted")
+	}
+
+	writeCertificate(t, certFile, keyFile, "gateway", time.Now())
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewReloader(certFile, keyFile, time.Second, zap.NewNop()); err == nil {
+		t.Fatal("invalid key accepted")
+	}
+}
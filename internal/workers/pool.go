@@ -0,0 +1,49 @@
+package workers
+
+import "sync"
+
+// Run calls fn for every index in [0, total) with at most limit calls running
+// at once, and blocks until all of them return. progress, if not nil, is
+// called after each completion with the number of finished items; calls are
+// serialized. A limit below 1 is treated as 1.
+func Run(limit, total int, fn func(i int), progress func(done, total int)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done int
+	)
+	slots := make(chan struct{}, limit)
+	for i := 0; i < total; i++ {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			fn(i)
+
+			if progress != nil {
+				mu.Lock()
+				done++
+				progress(done, total)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// ProgressStep returns how many completions apart progress should be reported
+// to log roughly every 10% of total.
+func ProgressStep(total int) int {
+	if step := total / 10; step > 1 {
+		return step
+	}
+	return 1
+}
@@ -0,0 +1,625 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/http/httpguts"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const redactedValue = "[REDACTED]"
+
+// Config holds the gateway settings read from AG_* environment variables.
+type Config struct {
+	Server    ServerConfig    `json:"server"`
+	Proxy     ProxyConfig     `json:"proxy"`
+	Debug     DebugConfig     `json:"debug"`
+	Security  SecurityConfig  `json:"security"`
+	CORS      CORSConfig      `json:"cors"`
+	Auth      AuthConfig      `json:"auth"`
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	Routes    RoutesConfig    `json:"routes"`
+	Record    RecordConfig    `json:"record"`
+}
+
+type ServerConfig struct {
+	// PreShutdownDelay é o tempo entre marcar o readiness como DOWN e começar o drain
+	PreShutdownDelay time.Duration `json:"preShutdownDelay"`
+	ShutdownTimeout  time.Duration `json:"shutdownTimeout"`
+	// ProxyDrainTimeout é quanto o shutdown espera as chamadas ativas aos backends antes de cancelá-las
+	ProxyDrainTimeout time.Duration `json:"proxyDrainTimeout"`
+	// MaxPathLength limita o tamanho do path da requisição (414 acima disso); 0 desabilita
+	MaxPathLength int `json:"maxPathLength"`
+	// MaxHeaderCount e MaxHeaderBytes limitam os headers da requisição (431 acima disso); 0 desabilita.
+	// MaxHeaderBytes também é aplicado ao http.Server
+	MaxHeaderCount int `json:"maxHeaderCount"`
+	MaxHeaderBytes int `json:"maxHeaderBytes"`
+	// MaxInFlight limita as requisições processadas ao mesmo tempo (503 acima disso); 0 é ilimitado
+	MaxInFlight int `json:"maxInFlight"`
+	// ResponseHeaders são adicionados a todas as respostas; {hostname} nos valores vira o hostname da instância
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+	// MethodOverrideHeader permite que requisições POST informem o método real (ex.: X-HTTP-Method-Override); vazio desabilita
+	MethodOverrideHeader string `json:"methodOverrideHeader"`
+	// ReadyAfterWarmup só marca o readiness como UP depois do preconnect dos backends
+	ReadyAfterWarmup bool `json:"readyAfterWarmup"`
+	// BulkConcurrency limita as operações em lote executadas em paralelo (ex.: preconnect dos backends)
+	BulkConcurrency int `json:"bulkConcurrency"`
+	// TLSCertFile/TLSKeyFile servem o gateway via HTTPS; os arquivos são relidos quando mudam, a cada TLSReloadInterval
+	TLSCertFile       string        `json:"tlsCertFile"`
+	TLSKeyFile        string        `json:"tlsKeyFile"`
+	TLSReloadInterval time.Duration `json:"tlsReloadInterval"`
+	// ReadinessConcurrency limita as verificações de dependências em paralelo e ReadinessTimeout é o prazo total do probe
+	ReadinessConcurrency int           `json:"readinessConcurrency"`
+	ReadinessTimeout     time.Duration `json:"readinessTimeout"`
+	// AdminResetEnabled expõe POST /admin/reset, que limpa caches e contadores (testes e recuperação)
+	AdminResetEnabled bool `json:"adminResetEnabled"`
+	// RequestIDInboundHeaders são os headers aceitos com o ID da requisição, em ordem de preferência;
+	// RequestIDHeader é o nome usado ao repassar o ID ao backend e na resposta
+	RequestIDInboundHeaders []string `json:"requestIDInboundHeaders"`
+	RequestIDHeader         string   `json:"requestIDHeader"`
+}
+
+type ProxyConfig struct {
+	// CACertFile e CACertPEM adicionam CAs confiáveis para backends HTTPS
+	CACertFile string `json:"caCertFile"`
+	CACertPEM  string `json:"caCertPEM"`
+	// CAAppendSystem mantém as CAs do sistema junto às configuradas
+	CAAppendSystem bool `json:"caAppendSystem"`
+	// MaxReplayBodyBytes limita o body mantido em memória para ser reenviado (ex.: espelhamento)
+	MaxReplayBodyBytes int64 `json:"maxReplayBodyBytes"`
+	// ErrorContentTypes são os formatos aceitos nos erros gerados pelo gateway; o primeiro é o padrão
+	ErrorContentTypes []string `json:"errorContentTypes"`
+	// Preconnect abre conexões com os backends na inicialização e no cadastro de rotas
+	Preconnect bool `json:"preconnect"`
+	// FlushInterval é a frequência com que o corpo da resposta do backend é enviado ao cliente durante a cópia;
+	// 0 mantém o buffer até o fim (exceto respostas de streaming) e negativo envia após cada escrita
+	FlushInterval time.Duration `json:"flushInterval"`
+	// CoalesceMaxBodyBytes limita a resposta compartilhada entre requisições agrupadas
+	CoalesceMaxBodyBytes int64 `json:"coalesceMaxBodyBytes"`
+	// ResponseCacheMaxEntries e ResponseCacheMaxBodyBytes limitam o cache de respostas das rotas com cacheResponses
+	ResponseCacheMaxEntries   int   `json:"responseCacheMaxEntries"`
+	ResponseCacheMaxBodyBytes int64 `json:"responseCacheMaxBodyBytes"`
+	// ResponseHeaderAllowlist, se definida, mantém apenas esses headers da resposta do backend
+	ResponseHeaderAllowlist []string `json:"responseHeaderAllowlist"`
+	ResponseHeaderDenylist  []string `json:"responseHeaderDenylist"`
+	// SchemaMaxBodyBytes limita o body lido para validação contra o requestSchema da rota
+	SchemaMaxBodyBytes int64 `json:"schemaMaxBodyBytes"`
+	// CacheableMethods são os métodos cujas respostas podem ser compartilhadas (coalescing e cache de respostas)
+	CacheableMethods []string `json:"cacheableMethods"`
+	// DNSCacheTTL mantém os IPs resolvidos dos backends em cache; 0 desabilita
+	DNSCacheTTL      time.Duration `json:"dnsCacheTTL"`
+	DNSLookupTimeout time.Duration `json:"dnsLookupTimeout"`
+	DialTimeout      time.Duration `json:"dialTimeout"`
+	// RequestTimeout limita cada chamada ao backend; 0 desabilita
+	RequestTimeout time.Duration `json:"requestTimeout"`
+	// AdaptiveTimeout*: rotas com adaptiveTimeout usam Multiplier × p99 da latência, limitado a Min/Max,
+	// e o RequestTimeout enquanto não houver MinSamples amostras
+	AdaptiveTimeoutMultiplier float64       `json:"adaptiveTimeoutMultiplier"`
+	AdaptiveTimeoutMin        time.Duration `json:"adaptiveTimeoutMin"`
+	AdaptiveTimeoutMax        time.Duration `json:"adaptiveTimeoutMax"`
+	AdaptiveTimeoutMinSamples int           `json:"adaptiveTimeoutMinSamples"`
+	// OutlierConsecutiveFailures falhas seguidas (5xx ou erro de conexão) tiram uma instância de upstreams
+	// do balanceamento por OutlierEjectionTime; 0 desabilita
+	OutlierConsecutiveFailures int           `json:"outlierConsecutiveFailures"`
+	OutlierEjectionTime        time.Duration `json:"outlierEjectionTime"`
+	// UpstreamProxy (http://, https:// ou socks5://, com usuário:senha opcionais) é usado para alcançar os backends;
+	// vazio mantém HTTP_PROXY/HTTPS_PROXY/NO_PROXY do ambiente
+	UpstreamProxy string `json:"upstreamProxy"`
+	// NoProxy lista hosts, domínios (.exemplo.com) e CIDRs acessados sem o UpstreamProxy
+	NoProxy []string `json:"noProxy"`
+}
+
+type DebugConfig struct {
+	// RecentErrorsEnabled habilita o buffer exposto em /admin/debug/recent-errors
+	RecentErrorsEnabled bool `json:"recentErrorsEnabled"`
+	RecentErrorsSize    int  `json:"recentErrorsSize"`
+}
+
+type SecurityConfig struct {
+	HeadersEnabled bool `json:"headersEnabled"`
+}
+
+type CORSConfig struct {
+	Enabled        bool          `json:"enabled"`
+	AllowedOrigins []string      `json:"allowedOrigins"`
+	AllowedMethods []string      `json:"allowedMethods"`
+	AllowedHeaders []string      `json:"allowedHeaders"`
+	MaxAge         time.Duration `json:"maxAge"`
+}
+
+type AuthConfig struct {
+	// Enabled=false desliga a validação de tokens (redes internas/confiáveis); as requisições viram AnonymousUser
+	Enabled       bool   `json:"enabled"`
+	AnonymousUser string `json:"anonymousUser"`
+	// AllowAnonymousAdmin libera /admin sem token quando a autenticação está desligada
+	AllowAnonymousAdmin bool `json:"allowAnonymousAdmin"`
+	// JWTSecret assina e valida os tokens; vazio mantém a chave padrão insegura
+	JWTSecret string `json:"jwtSecret"`
+	// TrustedHeader identifica o usuário já autenticado pela malha; vazio desabilita
+	TrustedHeader     string   `json:"trustedHeader"`
+	TrustedProxyCIDRs []string `json:"trustedProxyCIDRs"`
+	// Mode escolhe como os bearer tokens são validados: jwt (padrão) ou introspection (RFC 7662)
+	Mode                      string `json:"mode"`
+	IntrospectionURL          string `json:"introspectionURL"`
+	IntrospectionClientID     string `json:"introspectionClientID"`
+	IntrospectionClientSecret string `json:"introspectionClientSecret"`
+	// IntrospectionCacheTTL limita por quanto tempo um token ativo fica em cache (nunca além do exp)
+	IntrospectionCacheTTL time.Duration `json:"introspectionCacheTTL"`
+	// JWKSURL habilita tokens RS256 de IdPs externos, validados com as chaves publicadas nessa URL
+	JWKSURL string `json:"jwksURL"`
+	// Issuer e Audience, se definidos, são exigidos nos claims iss/aud desses tokens
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+}
+
+// Token validation modes.
+const (
+	AuthModeJWT           = "jwt"
+	AuthModeIntrospection = "introspection"
+)
+
+// Rate-limit algorithms for the per-route limit.
+const (
+	RateLimitStrategyTokenBucket   = "token_bucket"
+	RateLimitStrategySlidingWindow = "sliding_window"
+)
+
+type RateLimitConfig struct {
+	// DefaultLimit requisições por DefaultPeriod aplicadas a cada rota do proxy; 0 desabilita
+	DefaultLimit  int           `json:"defaultLimit"`
+	DefaultPeriod time.Duration `json:"defaultPeriod"`
+	// Strategy é o algoritmo do limite por rota: "token_bucket" ou "sliding_window"
+	Strategy string `json:"strategy"`
+	// VisitorTTL remove da memória o limite por IP de clientes sem requisições há mais tempo que isso; 0 nunca remove
+	VisitorTTL time.Duration `json:"visitorTTL"`
+	// Methods restringe os métodos que contam para o rate limit; vazio limita todos
+	Methods []string `json:"methods"`
+	// ResponseStatus/Body/ContentType definem a resposta de requisições limitadas; {retry_after} é substituído pelos segundos de espera
+	ResponseStatus      int    `json:"responseStatus"`
+	ResponseBody        string `json:"responseBody"`
+	ResponseContentType string `json:"responseContentType"`
+}
+
+type RoutesConfig struct {
+	// File é o arquivo de rotas carregado na inicialização (.json, .yaml ou .yml)
+	File string `json:"file"`
+	// ConflictPolicy define se rotas com padrões sobrepostos geram aviso ("warn") ou são rejeitadas ("reject")
+	ConflictPolicy string `json:"conflictPolicy"`
+	// TrailingSlash: "strict" diferencia /a e /a/, "redirect" redireciona para a forma cadastrada e "ignore" aceita as duas.
+	// O padrão é "redirect" porque é o que o gin.Default já fazia antes da opção existir
+	TrailingSlash string `json:"trailingSlash"`
+	// ReconcileInterval recarrega periodicamente as rotas do banco para corrigir caches divergentes; 0 desabilita
+	ReconcileInterval time.Duration `json:"reconcileInterval"`
+}
+
+// RecordConfig controls the test mode that records proxied traffic for replay.
+type RecordConfig struct {
+	Enabled bool   `json:"enabled"`
+	File    string `json:"file"`
+	// Routes limita a gravação a esses paths cadastrados; vazio grava todas as rotas
+	Routes       []string `json:"routes"`
+	MaxEntries   int      `json:"maxEntries"`
+	MaxBodyBytes int64    `json:"maxBodyBytes"`
+	// RedactHeaders e RedactQueryParams têm o valor substituído antes de gravar
+	RedactHeaders     []string `json:"redactHeaders"`
+	RedactQueryParams []string `json:"redactQueryParams"`
+}
+
+// LoadConfig reads the configuration from the environment, applying defaults
+// for any variable that is not set.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		Server: ServerConfig{
+			MethodOverrideHeader:    os.Getenv("AG_SERVER_METHOD_OVERRIDE_HEADER"),
+			RequestIDInboundHeaders: getEnvList("AG_SERVER_REQUEST_ID_INBOUND_HEADERS", []string{"X-Request-ID"}),
+			RequestIDHeader:         getEnv("AG_SERVER_REQUEST_ID_HEADER", "X-Request-ID"),
+			TLSCertFile:             os.Getenv("AG_SERVER_TLS_CERT_FILE"),
+			TLSKeyFile:              os.Getenv("AG_SERVER_TLS_KEY_FILE"),
+		},
+		Proxy: ProxyConfig{
+			CACertFile: os.Getenv("AG_PROXY_CA_CERT_FILE"),
+			CACertPEM:  os.Getenv("AG_PROXY_CA_CERT_PEM"),
+			ErrorContentTypes: getEnvList("AG_PROXY_ERROR_CONTENT_TYPES",
+				[]string{"application/json", "text/plain", "text/html"}),
+			ResponseHeaderAllowlist: getEnvList("AG_PROXY_RESPONSE_HEADER_ALLOWLIST", nil),
+			CacheableMethods:        getEnvList("AG_PROXY_CACHEABLE_METHODS", []string{"GET", "HEAD"}),
+			ResponseHeaderDenylist: getEnvList("AG_PROXY_RESPONSE_HEADER_DENYLIST",
+				[]string{"Server", "X-Powered-By", "X-AspNet-Version", "X-AspNetMvc-Version"}),
+			UpstreamProxy: os.Getenv("AG_PROXY_UPSTREAM_PROXY"),
+			NoProxy:       getEnvList("AG_PROXY_NO_PROXY", nil),
+		},
+		CORS: CORSConfig{
+			// Sem origens padrão: liberar outros sites exige uma escolha explícita
+			AllowedOrigins: getEnvList("AG_CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods: getEnvList("AG_CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+			AllowedHeaders: getEnvList("AG_CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "X-Request-ID"}),
+		},
+		Routes: RoutesConfig{
+			File:           getEnv("AG_ROUTES_FILE", "./routes/routes.json"),
+			ConflictPolicy: getEnv("AG_ROUTES_CONFLICT_POLICY", ConflictPolicyWarn),
+			TrailingSlash:  getEnv("AG_ROUTES_TRAILING_SLASH", TrailingSlashRedirect),
+		},
+		Auth: AuthConfig{
+			AnonymousUser:     getEnv("AG_AUTH_ANONYMOUS_USER", "anonymous"),
+			JWTSecret:         getEnv("AG_AUTH_JWT_SECRET", os.Getenv("JWT_SECRET_KEY")),
+			TrustedHeader:     os.Getenv("AG_AUTH_TRUSTED_HEADER"),
+			TrustedProxyCIDRs: getEnvList("AG_AUTH_TRUSTED_PROXY_CIDRS", nil),
+
+			Mode:                      getEnv("AG_AUTH_MODE", AuthModeJWT),
+			IntrospectionURL:          os.Getenv("AG_AUTH_INTROSPECTION_URL"),
+			IntrospectionClientID:     os.Getenv("AG_AUTH_INTROSPECTION_CLIENT_ID"),
+			IntrospectionClientSecret: os.Getenv("AG_AUTH_INTROSPECTION_CLIENT_SECRET"),
+			JWKSURL:                   os.Getenv("AG_AUTH_JWKS_URL"),
+			Issuer:                    os.Getenv("AG_AUTH_ISSUER"),
+			Audience:                  os.Getenv("AG_AUTH_AUDIENCE"),
+		},
+		RateLimit: RateLimitConfig{
+			Methods:             getEnvList("AG_RATE_LIMIT_METHODS", nil),
+			Strategy:            getEnv("AG_RATE_LIMIT_STRATEGY", RateLimitStrategyTokenBucket),
+			ResponseBody:        getEnv("AG_RATE_LIMIT_RESPONSE_BODY", `{"error":"Too Many Requests"}`),
+			ResponseContentType: getEnv("AG_RATE_LIMIT_RESPONSE_CONTENT_TYPE", "application/json; charset=utf-8"),
+		},
+		Record: RecordConfig{
+			File:   getEnv("AG_RECORD_FILE", "./recordings.jsonl"),
+			Routes: getEnvList("AG_RECORD_ROUTES", nil),
+			RedactHeaders: getEnvList("AG_RECORD_REDACT_HEADERS",
+				[]string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}),
+			RedactQueryParams: getEnvList("AG_RECORD_REDACT_QUERY_PARAMS",
+				[]string{"token", "access_token", "api_key", "apikey", "password"}),
+		},
+	}
+
+	// Valores sensíveis aceitam referências env:, file: ou de resolvers registrados (ex.: vault:)
+	if err := resolveSecretEnv("AG_AUTH_JWT_SECRET", &cfg.Auth.JWTSecret); err != nil {
+		return nil, err
+	}
+	if err := resolveSecretEnv("AG_PROXY_CA_CERT_PEM", &cfg.Proxy.CACertPEM); err != nil {
+		return nil, err
+	}
+	if err := resolveSecretEnv("AG_PROXY_UPSTREAM_PROXY", &cfg.Proxy.UpstreamProxy); err != nil {
+		return nil, err
+	}
+	if err := resolveSecretEnv("AG_AUTH_INTROSPECTION_CLIENT_SECRET", &cfg.Auth.IntrospectionClientSecret); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if cfg.Server.PreShutdownDelay, err = getEnvDuration("AG_SERVER_PRE_SHUTDOWN_DELAY", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Server.ShutdownTimeout, err = getEnvDuration("AG_SERVER_SHUTDOWN_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Server.ProxyDrainTimeout, err = getEnvDuration("AG_SERVER_PROXY_DRAIN_TIMEOUT", 25*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Server.MaxPathLength, err = getEnvInt("AG_SERVER_MAX_PATH_LENGTH", 8192); err != nil {
+		return nil, err
+	}
+	if cfg.Server.MaxHeaderCount, err = getEnvInt("AG_SERVER_MAX_HEADER_COUNT", 100); err != nil {
+		return nil, err
+	}
+	if cfg.Server.MaxHeaderBytes, err = getEnvInt("AG_SERVER_MAX_HEADER_BYTES", 32<<10); err != nil {
+		return nil, err
+	}
+	if cfg.Server.ResponseHeaders, err = getEnvMap("AG_SERVER_RESPONSE_HEADERS"); err != nil {
+		return nil, err
+	}
+	for name, value := range cfg.Server.ResponseHeaders {
+		if !httpguts.ValidHeaderFieldName(name) || !httpguts.ValidHeaderFieldValue(value) {
+			return nil, fmt.Errorf("invalid value for AG_SERVER_RESPONSE_HEADERS: header %q", name)
+		}
+	}
+	if cfg.Server.MaxInFlight, err = getEnvInt("AG_SERVER_MAX_IN_FLIGHT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.Server.ReadyAfterWarmup, err = getEnvBool("AG_SERVER_READY_AFTER_WARMUP", false); err != nil {
+		return nil, err
+	}
+	if cfg.Server.BulkConcurrency, err = getEnvInt("AG_SERVER_BULK_CONCURRENCY", 4); err != nil {
+		return nil, err
+	}
+	if cfg.Server.ReadinessConcurrency, err = getEnvInt("AG_SERVER_READINESS_CONCURRENCY", 4); err != nil {
+		return nil, err
+	}
+	if cfg.Server.ReadinessTimeout, err = getEnvDuration("AG_SERVER_READINESS_TIMEOUT", 2*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Server.AdminResetEnabled, err = getEnvBool("AG_SERVER_ADMIN_RESET_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.Server.TLSReloadInterval, err = getEnvDuration("AG_SERVER_TLS_RELOAD_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.CAAppendSystem, err = getEnvBool("AG_PROXY_CA_APPEND_SYSTEM", true); err != nil {
+		return nil, err
+	}
+
+	if cfg.Proxy.MaxReplayBodyBytes, err = getEnvInt64("AG_PROXY_MAX_REPLAY_BODY_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.Preconnect, err = getEnvBool("AG_PROXY_PRECONNECT", false); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.FlushInterval, err = getEnvDuration("AG_PROXY_FLUSH_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.CoalesceMaxBodyBytes, err = getEnvInt64("AG_PROXY_COALESCE_MAX_BODY_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.ResponseCacheMaxEntries, err = getEnvInt("AG_PROXY_RESPONSE_CACHE_MAX_ENTRIES", 10000); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.ResponseCacheMaxBodyBytes, err = getEnvInt64("AG_PROXY_RESPONSE_CACHE_MAX_BODY_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.SchemaMaxBodyBytes, err = getEnvInt64("AG_PROXY_SCHEMA_MAX_BODY_BYTES", 1<<20); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.DNSCacheTTL, err = getEnvDuration("AG_PROXY_DNS_CACHE_TTL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.DNSLookupTimeout, err = getEnvDuration("AG_PROXY_DNS_LOOKUP_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.DialTimeout, err = getEnvDuration("AG_PROXY_DIAL_TIMEOUT", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.RequestTimeout, err = getEnvDuration("AG_PROXY_REQUEST_TIMEOUT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.AdaptiveTimeoutMultiplier, err = getEnvFloat("AG_PROXY_ADAPTIVE_TIMEOUT_MULTIPLIER", 3); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.AdaptiveTimeoutMin, err = getEnvDuration("AG_PROXY_ADAPTIVE_TIMEOUT_MIN", 500*time.Millisecond); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.AdaptiveTimeoutMax, err = getEnvDuration("AG_PROXY_ADAPTIVE_TIMEOUT_MAX", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.AdaptiveTimeoutMinSamples, err = getEnvInt("AG_PROXY_ADAPTIVE_TIMEOUT_MIN_SAMPLES", 50); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.OutlierConsecutiveFailures, err = getEnvInt("AG_PROXY_OUTLIER_CONSECUTIVE_FAILURES", 5); err != nil {
+		return nil, err
+	}
+	if cfg.Proxy.OutlierEjectionTime, err = getEnvDuration("AG_PROXY_OUTLIER_EJECTION_TIME", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Routes.ReconcileInterval, err = getEnvDuration("AG_ROUTES_RECONCILE_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.Enabled, err = getEnvBool("AG_AUTH_ENABLED", true); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.AllowAnonymousAdmin, err = getEnvBool("AG_AUTH_ALLOW_ANONYMOUS_ADMIN", false); err != nil {
+		return nil, err
+	}
+	if cfg.Auth.IntrospectionCacheTTL, err = getEnvDuration("AG_AUTH_INTROSPECTION_CACHE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.DefaultLimit, err = getEnvInt("AG_RATE_LIMIT_DEFAULT_LIMIT", 600); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.DefaultPeriod, err = getEnvDuration("AG_RATE_LIMIT_DEFAULT_PERIOD", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.VisitorTTL, err = getEnvDuration("AG_RATE_LIMIT_VISITOR_TTL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.ResponseStatus, err = getEnvInt("AG_RATE_LIMIT_RESPONSE_STATUS", 429); err != nil {
+		return nil, err
+	}
+	if cfg.Debug.RecentErrorsEnabled, err = getEnvBool("AG_DEBUG_RECENT_ERRORS_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.Debug.RecentErrorsSize, err = getEnvInt("AG_DEBUG_RECENT_ERRORS_SIZE", 100); err != nil {
+		return nil, err
+	}
+
+	if cfg.Security.HeadersEnabled, err = getEnvBool("AG_SECURITY_HEADERS_ENABLED", true); err != nil {
+		return nil, err
+	}
+	if cfg.CORS.Enabled, err = getEnvBool("AG_CORS_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.CORS.MaxAge, err = getEnvDuration("AG_CORS_MAX_AGE", 12*time.Hour); err != nil {
+		return nil, err
+	}
+
+	if cfg.Record.Enabled, err = getEnvBool("AG_RECORD_ENABLED", false); err != nil {
+		return nil, err
+	}
+	if cfg.Record.MaxEntries, err = getEnvInt("AG_RECORD_MAX_ENTRIES", 1000); err != nil {
+		return nil, err
+	}
+	if cfg.Record.MaxBodyBytes, err = getEnvInt64("AG_RECORD_MAX_BODY_BYTES", 64<<10); err != nil {
+		return nil, err
+	}
+
+	if cfg.Proxy.UpstreamProxy != "" {
+		if err := ValidateUpstreamProxy(cfg.Proxy.UpstreamProxy); err != nil {
+			return nil, fmt.Errorf("invalid value for AG_PROXY_UPSTREAM_PROXY: %w", err)
+		}
+	}
+
+	switch cfg.Auth.Mode {
+	case AuthModeJWT:
+	case AuthModeIntrospection:
+		if cfg.Auth.IntrospectionURL == "" {
+			return nil, fmt.Errorf("AG_AUTH_INTROSPECTION_URL is required when AG_AUTH_MODE is %s", AuthModeIntrospection)
+		}
+	default:
+		return nil, fmt.Errorf("invalid value for AG_AUTH_MODE: %s", cfg.Auth.Mode)
+	}
+
+	if cfg.CORS.Enabled && len(cfg.CORS.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("AG_CORS_ALLOWED_ORIGINS is required when AG_CORS_ENABLED is true")
+	}
+
+	if s := cfg.RateLimit.Strategy; s != RateLimitStrategyTokenBucket && s != RateLimitStrategySlidingWindow {
+		return nil, fmt.Errorf("invalid value for AG_RATE_LIMIT_STRATEGY: %s", s)
+	}
+
+	if (cfg.Server.TLSCertFile == "") != (cfg.Server.TLSKeyFile == "") {
+		return nil, fmt.Errorf("AG_SERVER_TLS_CERT_FILE and AG_SERVER_TLS_KEY_FILE must be set together")
+	}
+
+	if cfg.Proxy.AdaptiveTimeoutMultiplier < 1 {
+		return nil, fmt.Errorf("invalid value for AG_PROXY_ADAPTIVE_TIMEOUT_MULTIPLIER: must be at least 1")
+	}
+	if cfg.Proxy.AdaptiveTimeoutMin > cfg.Proxy.AdaptiveTimeoutMax {
+		return nil, fmt.Errorf("invalid value for AG_PROXY_ADAPTIVE_TIMEOUT_MIN: greater than AG_PROXY_ADAPTIVE_TIMEOUT_MAX")
+	}
+
+	if s := cfg.RateLimit.ResponseStatus; s < 400 || s > 599 {
+		return nil, fmt.Errorf("invalid value for AG_RATE_LIMIT_RESPONSE_STATUS: %d", s)
+	}
+
+	for _, methods := range [][]string{cfg.Proxy.CacheableMethods, cfg.RateLimit.Methods} {
+		for _, m := range methods {
+			if !IsValidMethod(m) {
+				return nil, fmt.Errorf("invalid HTTP method in configuration: %s", m)
+			}
+		}
+	}
+
+	if p := cfg.Routes.ConflictPolicy; p != ConflictPolicyWarn && p != ConflictPolicyReject {
+		return nil, fmt.Errorf("invalid value for AG_ROUTES_CONFLICT_POLICY: %s", p)
+	}
+
+	switch cfg.Routes.TrailingSlash {
+	case TrailingSlashStrict, TrailingSlashRedirect, TrailingSlashIgnore:
+	default:
+		return nil, fmt.Errorf("invalid value for AG_ROUTES_TRAILING_SLASH: %s", cfg.Routes.TrailingSlash)
+	}
+
+	return cfg, nil
+}
+
+// Redacted returns a copy of the configuration with secret values masked, safe
+// to expose through the admin API.
+func (c *Config) Redacted() Config {
+	redacted := *c
+	redacted.Proxy.CACertPEM = redact(c.Proxy.CACertPEM)
+	redacted.Auth.JWTSecret = redact(c.Auth.JWTSecret)
+	redacted.Proxy.UpstreamProxy = redactURLPassword(c.Proxy.UpstreamProxy)
+	redacted.Auth.IntrospectionClientSecret = redact(c.Auth.IntrospectionClientSecret)
+	return redacted
+}
+
+// redactURLPassword masks only the password of a URL, keeping the rest
+// readable for troubleshooting.
+func redactURLPassword(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return redact(value)
+	}
+	return u.Redacted()
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+func getEnv(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+func getEnvList(key string, def []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return def
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvBool(key string, def bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return b, nil
+}
+
+func getEnvInt(key string, def int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return i, nil
+}
+
+func getEnvInt64(key string, def int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return i, nil
+}
+
+func getEnvFloat(key string, def float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// getEnvMap reads a JSON object of strings, e.g. {"X-Served-By": "{hostname}"}.
+func getEnvMap(key string) (map[string]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(value), &m); err != nil {
+		return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return m, nil
+}
+
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return d, nil
+}
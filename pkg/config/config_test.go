@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSRequiresOptIn(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CORS.Enabled || len(cfg.CORS.AllowedOrigins) != 0 {
+		t.Fatalf("CORS enabled %v with origins %q by default, want it off with no origins", cfg.CORS.Enabled, cfg.CORS.AllowedOrigins)
+	}
+
+	t.Setenv("AG_CORS_ENABLED", "true")
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("CORS enabled without AG_CORS_ALLOWED_ORIGINS accepted")
+	}
+
+	t.Setenv("AG_CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.CORS.Enabled || len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://app.example.com" {
+		t.Fatalf("got enabled %v, origins %q", cfg.CORS.Enabled, cfg.CORS.AllowedOrigins)
+	}
+}
+
+func TestLoadConfigEnvOverrides(t *testing.T) {
+	// Uma variável por seção da configuração
+	tests := []struct {
+		key   string
+		value string
+		got   func(*Config) interface{}
+		want  interface{}
+	}{
+		{"AG_SERVER_MAX_PATH_LENGTH", "1024", func(c *Config) interface{} { return c.Server.MaxPathLength }, 1024},
+		{"AG_PROXY_OUTLIER_EJECTION_TIME", "45s", func(c *Config) interface{} { return c.Proxy.OutlierEjectionTime }, 45 * time.Second},
+		{"AG_PROXY_ADAPTIVE_TIMEOUT_MULTIPLIER", "1.5", func(c *Config) interface{} { return c.Proxy.AdaptiveTimeoutMultiplier }, 1.5},
+		{"AG_DEBUG_RECENT_ERRORS_SIZE", "7", func(c *Config) interface{} { return c.Debug.RecentErrorsSize }, 7},
+		{"AG_SECURITY_HEADERS_ENABLED", "false", func(c *Config) interface{} { return c.Security.HeadersEnabled }, false},
+		{"AG_CORS_ALLOWED_METHODS", "GET, POST", func(c *Config) interface{} { return c.CORS.AllowedMethods }, []string{"GET", "POST"}},
+		{"AG_AUTH_ALLOW_ANONYMOUS_ADMIN", "true", func(c *Config) interface{} { return c.Auth.AllowAnonymousAdmin }, true},
+		{"AG_RATE_LIMIT_STRATEGY", RateLimitStrategySlidingWindow, func(c *Config) interface{} { return c.RateLimit.Strategy }, RateLimitStrategySlidingWindow},
+		{"AG_ROUTES_TRAILING_SLASH", TrailingSlashIgnore, func(c *Config) interface{} { return c.Routes.TrailingSlash }, TrailingSlashIgnore},
+		{"AG_RECORD_MAX_BODY_BYTES", "2048", func(c *Config) interface{} { return c.Record.MaxBodyBytes }, int64(2048)},
+	}
+	for _, tt := range tests {
+		t.Setenv(tt.key, tt.value)
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if got := tt.got(cfg); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s=%q: got %v, want %v", tt.key, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadConfigInvalidValues(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{"AG_SERVER_MAX_PATH_LENGTH", "long"},
+		{"AG_AUTH_ENABLED", "maybe"},
+		{"AG_PROXY_DIAL_TIMEOUT", "soon"},
+		{"AG_RECORD_MAX_BODY_BYTES", "1.5"},
+		{"AG_PROXY_ADAPTIVE_TIMEOUT_MULTIPLIER", "triple"},
+		{"AG_RATE_LIMIT_STRATEGY", "leaky_bucket"},
+		{"AG_RATE_LIMIT_RESPONSE_STATUS", "200"},
+		{"AG_ROUTES_TRAILING_SLASH", "sometimes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			t.Setenv(tt.key, tt.value)
+			_, err := LoadConfig()
+			if err == nil {
+				t.Fatalf("%s=%q accepted", tt.key, tt.value)
+			}
+			if !strings.Contains(err.Error(), tt.key) {
+				t.Fatalf("error %q doesn't name %s", err, tt.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ConflictPolicyWarn   = "warn"
+	ConflictPolicyReject = "reject"
+)
+
+type RouteConflict struct {
+	Path          string `json:"path"`
+	ConflictsWith string `json:"conflictsWith"`
+	Reason        string `json:"reason"`
+}
+
+func (c RouteConflict) String() string {
+	return fmt.Sprintf("%s conflicts with %s: %s", c.Path, c.ConflictsWith, c.Reason)
+}
+
+// FindRouteConflicts reports candidate routes whose path patterns overlap with
+// an existing route or with another candidate. Paths follow gin syntax, where
+// ":name" matches one segment and "*name" matches the rest of the path.
+func FindRouteConflicts(existing []*Route, candidates []Route) []RouteConflict {
+	var conflicts []RouteConflict
+
+	for i, candidate := range candidates {
+		for _, route := range existing {
+			if route.Path == candidate.Path {
+				continue // atualização da mesma rota, já tratada pelo banco
+			}
+			if patternsOverlap(candidate.Path, route.Path) {
+				conflicts = append(conflicts, RouteConflict{
+					Path:          candidate.Path,
+					ConflictsWith: route.Path,
+					Reason:        "patterns overlap",
+				})
+			}
+		}
+
+		for _, other := range candidates[i+1:] {
+			reason := ""
+			switch {
+			case other.Path == candidate.Path:
+				reason = "duplicate path"
+			case patternsOverlap(candidate.Path, other.Path):
+				reason = "patterns overlap"
+			}
+			if reason != "" {
+				conflicts = append(conflicts, RouteConflict{
+					Path:          candidate.Path,
+					ConflictsWith: other.Path,
+					Reason:        reason,
+				})
+			}
+		}
+	}
+
+	return conflicts
+}
+
+// patternsOverlap reports whether at least one request path matches both
+// patterns.
+func patternsOverlap(a, b string) bool {
+	as, bs := splitPattern(a), splitPattern(b)
+	for i := 0; ; i++ {
+		aEnd, bEnd := i >= len(as), i >= len(bs)
+		if !aEnd && strings.HasPrefix(as[i], "*") || !bEnd && strings.HasPrefix(bs[i], "*") {
+			return true
+		}
+		if aEnd || bEnd {
+			return aEnd && bEnd
+		}
+		if strings.HasPrefix(as[i], ":") || strings.HasPrefix(bs[i], ":") {
+			continue
+		}
+		if as[i] != bs[i] {
+			return false
+		}
+	}
+}
+
+func splitPattern(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
@@ -1,40 +1,268 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	TrailingSlashStrict   = "strict"
+	TrailingSlashRedirect = "redirect"
+	TrailingSlashIgnore   = "ignore"
+)
+
 type Route struct {
-	Path            string        `json:"path" gorm:"type:varchar(255)"`
-	ServiceURL      string        `json:"serviceURL" gorm:"type:varchar(255)"`
-	Methods         []string      `json:"methods" gorm:"type:json"`
-	Headers         []string      `json:"headers" gorm:"type:json"`
-	Description     string        `json:"description" gorm:"type:text"`
-	IsActive        bool          `json:"isActive"`
-	CallCount       int64         `json:"callCount"`
-	TotalResponse   time.Duration `json:"totalResponse"`
-	RequiredHeaders []string      `json:"requiredHeaders" gorm:"type:json"`
+	Path            string        `json:"path" yaml:"path" gorm:"type:varchar(255)"`
+	ServiceURL      string        `json:"serviceURL" yaml:"serviceURL" gorm:"type:varchar(255)"`
+	Methods         []string      `json:"methods" yaml:"methods" gorm:"type:json"`
+	Headers         []string      `json:"headers" yaml:"headers" gorm:"type:json"`
+	Description     string        `json:"description" yaml:"description" gorm:"type:text"`
+	IsActive        bool          `json:"isActive" yaml:"isActive"`
+	CallCount       int64         `json:"callCount" yaml:"callCount"`
+	TotalResponse   time.Duration `json:"totalResponse" yaml:"totalResponse"`
+	RequiredHeaders []string      `json:"requiredHeaders" yaml:"requiredHeaders" gorm:"type:json"`
+	// MirrorURL recebe uma cópia assíncrona do tráfego; a resposta é descartada.
+	MirrorURL string `json:"mirrorURL" yaml:"mirrorURL" gorm:"type:varchar(255)"`
+	// MirrorPercent define a amostra espelhada (1-100); 0 espelha todas as requisições.
+	MirrorPercent int `json:"mirrorPercent" yaml:"mirrorPercent"`
+	// CACertFile substitui o bundle de CAs global para backends com CA própria.
+	CACertFile string `json:"caCertFile" yaml:"caCertFile" gorm:"type:varchar(255)"`
+	// RequireHTTPS recusa requisições que não chegaram via HTTPS (considerando X-Forwarded-Proto).
+	RequireHTTPS bool `json:"requireHTTPS" yaml:"requireHTTPS" gorm:"column:require_https"`
+	// Tags e Group organizam as rotas por time/domínio
+	Tags  []string `json:"tags" yaml:"tags" gorm:"type:json"`
+	Group string   `json:"group" yaml:"group" gorm:"column:route_group;type:varchar(255)"`
+	// Coalesce faz GETs idênticos e simultâneos compartilharem uma única chamada ao backend.
+	Coalesce bool `json:"coalesce" yaml:"coalesce"`
+	// Streaming envia cada escrita do backend ao cliente imediatamente, para downloads grandes e respostas contínuas
+	Streaming bool `json:"streaming" yaml:"streaming"`
+	// CacheResponses guarda as respostas do backend pelo tempo permitido no Cache-Control (max-age/s-maxage)
+	CacheResponses bool `json:"cacheResponses" yaml:"cacheResponses"`
+	// ResponseHeaderAllowlist/Denylist filtram os headers da resposta do backend, somando-se à configuração global.
+	ResponseHeaderAllowlist []string `json:"responseHeaderAllowlist" yaml:"responseHeaderAllowlist" gorm:"type:json"`
+	ResponseHeaderDenylist  []string `json:"responseHeaderDenylist" yaml:"responseHeaderDenylist" gorm:"type:json"`
+	// RequestSchema é um JSON Schema opcional que o body da requisição precisa satisfazer
+	RequestSchema string `json:"requestSchema" yaml:"requestSchema"`
+	// PreserveHostHeader repassa o Host original do cliente em vez do host do serviceURL
+	PreserveHostHeader bool `json:"preserveHostHeader" yaml:"preserveHostHeader"`
+	// RateLimit requisições por RateLimitPeriod sobrescrevem o limite padrão da rota; 0 usa AG_RATE_LIMIT_DEFAULT_*
+	RateLimit       int           `json:"rateLimit" yaml:"rateLimit"`
+	RateLimitPeriod time.Duration `json:"rateLimitPeriod" yaml:"rateLimitPeriod"`
+	// RateLimitMethods restringe os métodos que contam para o rate limit da rota; vazio usa a configuração global
+	RateLimitMethods []string `json:"rateLimitMethods" yaml:"rateLimitMethods" gorm:"type:json"`
+	// Deprecated e SunsetAt anunciam aos clientes que a rota será removida (headers Deprecation e Sunset)
+	Deprecated bool       `json:"deprecated" yaml:"deprecated"`
+	SunsetAt   *time.Time `json:"sunsetAt,omitempty" yaml:"sunsetAt,omitempty"`
+	// Version é incrementada a cada alteração; atualizações precisam informar a versão lida
+	Version int64 `json:"version" yaml:"-" gorm:"not null;default:1"`
+	// UpstreamProxy envia o tráfego da rota por um proxy HTTP/SOCKS5 próprio; "direct" ignora o proxy global
+	UpstreamProxy string `json:"upstreamProxy" yaml:"upstreamProxy" gorm:"type:varchar(255)"`
+	// Timeout limita cada chamada ao backend da rota; 0 usa o timeout global (AG_PROXY_REQUEST_TIMEOUT)
+	Timeout time.Duration `json:"timeout" yaml:"timeout"`
+	// AdaptiveTimeout deriva o timeout do backend do p99 recente da rota em vez de usar o timeout fixo
+	AdaptiveTimeout bool `json:"adaptiveTimeout" yaml:"adaptiveTimeout"`
+	// Upstreams distribui o tráfego da rota entre várias instâncias por peso; vazio usa apenas o serviceURL
+	Upstreams []Upstream `json:"upstreams" yaml:"upstreams" gorm:"type:json"`
+	// ErrorPages substitui as respostas de erro do backend com esses status por uma resposta do gateway
+	ErrorPages []ErrorPage `json:"errorPages" yaml:"errorPages" gorm:"type:json"`
+}
+
+// Upstream is one backend instance of a route balanced across several.
+type Upstream struct {
+	URL string `json:"url" yaml:"url"`
+	// Weight é a fatia relativa do tráfego da instância; 0 vale 1
+	Weight int `json:"weight,omitempty" yaml:"weight,omitempty"`
+}
+
+// EffectiveWeight returns the weight used for balancing.
+func (u Upstream) EffectiveWeight() int {
+	if u.Weight <= 0 {
+		return 1
+	}
+	return u.Weight
+}
+
+// Backends returns the upstreams the route balances across. A route without
+// upstreams has its ServiceURL as the single backend.
+func (r *Route) Backends() []Upstream {
+	if len(r.Upstreams) > 0 {
+		return r.Upstreams
+	}
+	return []Upstream{{URL: r.ServiceURL, Weight: 1}}
+}
+
+// ErrorPage replaces a backend error response with a gateway-controlled one,
+// hiding backend internals behind a uniform error page.
+type ErrorPage struct {
+	// Status é o status do backend substituído (400-599)
+	Status int `json:"status" yaml:"status"`
+	// ResponseStatus é o status devolvido ao cliente; 0 mantém o do backend
+	ResponseStatus int    `json:"responseStatus,omitempty" yaml:"responseStatus,omitempty"`
+	Body           string `json:"body" yaml:"body"`
+	ContentType    string `json:"contentType,omitempty" yaml:"contentType,omitempty"`
 }
 
+// ErrorPageFor returns the error page configured for a backend status, or nil
+// when the response passes through unchanged.
+func (r *Route) ErrorPageFor(status int) *ErrorPage {
+	for i := range r.ErrorPages {
+		if r.ErrorPages[i].Status == status {
+			return &r.ErrorPages[i]
+		}
+	}
+	return nil
+}
+
+// UpstreamProxyDirect makes a route reach its backend without the global
+// upstream proxy.
+const UpstreamProxyDirect = "direct"
+
 func (r *Route) Validate() error {
 	if r.Path == "" {
 		return errors.New("path is required")
 	}
-	if r.ServiceURL == "" {
-		return errors.New("serviceURL is required")
+	if r.ServiceURL == "" && len(r.Upstreams) == 0 {
+		return errors.New("serviceURL or upstreams is required")
+	}
+	for _, upstream := range r.Upstreams {
+		u, err := url.Parse(upstream.URL)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("invalid upstreams url: %q", upstream.URL)
+		}
+		if upstream.Weight < 0 {
+			return fmt.Errorf("upstreams weight must not be negative: %q", upstream.URL)
+		}
 	}
 	if len(r.Methods) == 0 {
 		return errors.New("at least one HTTP method is required")
 	}
+	for _, methods := range [][]string{r.Methods, r.RateLimitMethods} {
+		for _, m := range methods {
+			if !IsValidMethod(m) {
+				return fmt.Errorf("invalid HTTP method: %q", m)
+			}
+		}
+	}
+	if r.MirrorPercent < 0 || r.MirrorPercent > 100 {
+		return errors.New("mirrorPercent must be between 0 and 100")
+	}
+	if r.RequestSchema != "" && !json.Valid([]byte(r.RequestSchema)) {
+		return errors.New("requestSchema must be a valid JSON document")
+	}
+	if r.RateLimit < 0 || r.RateLimitPeriod < 0 {
+		return errors.New("rateLimit and rateLimitPeriod must not be negative")
+	}
+	if r.Timeout < 0 {
+		return errors.New("timeout must not be negative")
+	}
+	for _, page := range r.ErrorPages {
+		if page.Status < 400 || page.Status > 599 {
+			return fmt.Errorf("errorPages status must be between 400 and 599: %d", page.Status)
+		}
+		if page.ResponseStatus != 0 && (page.ResponseStatus < 100 || page.ResponseStatus > 599) {
+			return fmt.Errorf("invalid errorPages responseStatus: %d", page.ResponseStatus)
+		}
+	}
+	if r.UpstreamProxy != "" && r.UpstreamProxy != UpstreamProxyDirect {
+		if err := ValidateUpstreamProxy(r.UpstreamProxy); err != nil {
+			return fmt.Errorf("invalid upstreamProxy: %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateUpstreamProxy checks that raw is an http, https or socks5 proxy URL.
+// Credentials for proxy authentication go in the URL user info.
+func ValidateUpstreamProxy(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return errors.New("proxy host is required")
+	}
 	return nil
 }
 
+var validMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodPost:    true,
+	http.MethodPut:     true,
+	http.MethodPatch:   true,
+	http.MethodDelete:  true,
+	http.MethodConnect: true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+}
+
+// IsValidMethod reports whether method is a standard HTTP verb, ignoring case.
+func IsValidMethod(method string) bool {
+	return validMethods[strings.ToUpper(method)]
+}
+
 func (r *Route) IsMethodAllowed(method string) bool {
-	for _, m := range r.Methods {
-		if m == method {
+	return MethodIn(method, r.Methods)
+}
+
+// MethodIn reports whether method is in methods, ignoring case.
+func MethodIn(method string, methods []string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsRateLimited reports whether requests with the given method count toward
+// rate limits. The route's list takes precedence over the global defaults; if
+// both are empty every method is limited.
+func (r *Route) IsRateLimited(method string, defaults []string) bool {
+	methods := r.RateLimitMethods
+	if len(methods) == 0 {
+		methods = defaults
+	}
+	return len(methods) == 0 || MethodIn(method, methods)
+}
+
+func (r *Route) HasTag(tag string) bool {
+	for _, t := range r.Tags {
+		if t == tag {
 			return true
 		}
 	}
 	return false
 }
+
+// MatchPaths returns the paths the route must be registered under for the
+// given trailing-slash policy.
+func (r *Route) MatchPaths(trailingSlash string) []string {
+	if trailingSlash == TrailingSlashIgnore {
+		if alt := ToggleTrailingSlash(r.Path); alt != r.Path {
+			return []string{r.Path, alt}
+		}
+	}
+	return []string{r.Path}
+}
+
+// ToggleTrailingSlash adds or removes the trailing slash of path.
+func ToggleTrailingSlash(path string) string {
+	if path == "/" || path == "" {
+		return path
+	}
+	if strings.HasSuffix(path, "/") {
+		return strings.TrimSuffix(path, "/")
+	}
+	return path + "/"
+}
@@ -0,0 +1,35 @@
+package config
+
+import "testing"
+
+func TestRouteValidateMethods(t *testing.T) {
+	tests := []struct {
+		methods []string
+		valid   bool
+	}{
+		{[]string{"GET", "POST"}, true},
+		{[]string{"get", "Delete"}, true},
+		{nil, false},
+		{[]string{}, false},
+		{[]string{"GET", "FETCH"}, false},
+		{[]string{""}, false},
+	}
+	for _, tt := range tests {
+		route := Route{Path: "/items", ServiceURL: "http://items.internal", Methods: tt.methods}
+		if err := route.Validate(); (err == nil) != tt.valid {
+			t.Fatalf("methods %q: Validate() = %v, want valid=%v", tt.methods, err, tt.valid)
+		}
+	}
+}
+
+func TestRouteIsMethodAllowedIgnoresCase(t *testing.T) {
+	route := Route{Methods: []string{"get", "POST"}}
+	for _, method := range []string{"GET", "get", "post", "Post"} {
+		if !route.IsMethodAllowed(method) {
+			t.Fatalf("%s not allowed", method)
+		}
+	}
+	if route.IsMethodAllowed("DELETE") {
+		t.Fatal("DELETE allowed")
+	}
+}
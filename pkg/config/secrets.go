@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// SecretResolver turns a secret reference (the part after "scheme:") into
+// the secret value.
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc adapts a function to SecretResolver.
+type SecretResolverFunc func(ref string) (string, error)
+
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	secretResolversMu sync.RWMutex
+	secretResolvers   = map[string]SecretResolver{
+		"env":  SecretResolverFunc(resolveEnvSecret),
+		"file": SecretResolverFunc(resolveFileSecret),
+	}
+)
+
+// RegisterSecretResolver makes LoadConfig resolve values prefixed with
+// "scheme:" through r, e.g. a Vault client registered as "vault". It must be
+// called before LoadConfig.
+func RegisterSecretResolver(scheme string, r SecretResolver) {
+	secretResolversMu.Lock()
+	defer secretResolversMu.Unlock()
+	secretResolvers[scheme] = r
+}
+
+// ResolveSecret resolves value if it starts with "env:", "file:" or another
+// registered scheme; any other value is returned unchanged as a literal.
+func ResolveSecret(value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+
+	secretResolversMu.RLock()
+	resolver, registered := secretResolvers[scheme]
+	secretResolversMu.RUnlock()
+
+	if !registered {
+		// "vault:" é um esquema conhecido; sem resolver registrado é erro de configuração, não literal
+		if scheme == "vault" {
+			return "", fmt.Errorf("no secret resolver registered for %q", scheme)
+		}
+		return value, nil
+	}
+	return resolver.Resolve(ref)
+}
+
+func resolveEnvSecret(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func resolveFileSecret(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Arquivos de segredo montados (ex.: Kubernetes) costumam terminar com quebra de linha
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveSecretEnv resolves the secret referenced by the value at *target,
+// read from the env var key.
+func resolveSecretEnv(key string, target *string) error {
+	value, err := ResolveSecret(*target)
+	if err != nil {
+		return fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	*target = value
+	return nil
+}